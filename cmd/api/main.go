@@ -1,16 +1,26 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	_ "github.com/lib/pq"
 	"github.com/rx-rz/65ch/internal/config"
+	"github.com/rx-rz/65ch/internal/data"
 	"github.com/rx-rz/65ch/internal/jsonlog"
 	"github.com/rx-rz/65ch/internal/rest"
+	"github.com/rx-rz/65ch/internal/views"
+	"github.com/rx-rz/65ch/internal/worker"
 	"log"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 )
 
 func main() {
-	_, err := config.LoadEnvVariables()
+	envs, err := config.LoadEnvVariables()
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -18,23 +28,154 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
+	// PrintFatal only logs, and nothing below can run without its config
+	fatal := func(err error) {
+		logger.PrintFatal(err, nil)
+		os.Exit(1)
+	}
 	logger.PrintInfo("connecting to db", map[string]string{})
 	db, err := config.InitializeDB()
-	cfg := config.New(db, logger)
 	if err != nil {
-		logger.PrintFatal(err, nil)
+		fatal(err)
 	}
-	api := rest.InitializeAPI(cfg)
-	logger.PrintInfo("starting server", map[string]string{
-		"addr": api.Addr,
-	})
-	err = api.ListenAndServe()
+	mail, err := config.InitializeMailer(envs)
 	if err != nil {
-		logger.PrintFatal(err, nil)
+		fatal(err)
+	}
+	keyring, err := config.InitializeKeyring(envs)
+	if err != nil {
+		fatal(err)
+	}
+	passwordPolicy, err := config.InitializePasswordPolicy(envs)
+	if err != nil {
+		fatal(err)
+	}
+	cfg := config.New(db, logger, mail, keyring, envs)
+	cfg.PasswordPolicy = passwordPolicy
+
+	publishPeriod, err := parsePeriod("PUBLISH_PERIOD", envs.PublishPeriod)
+	if err != nil {
+		fatal(err)
+	}
+	trendingPeriod, err := parsePeriod("TRENDING_PERIOD", envs.TrendingPeriod)
+	if err != nil {
+		fatal(err)
 	}
-	defer func() {
-		if err := db.Close(); err != nil {
-			logger.PrintFatal(err, nil)
+	models := data.NewModels(db)
+	if envs.AdminEmail != "" {
+		if err := bootstrapAdmin(&models.Users, logger, envs.AdminEmail); err != nil {
+			fatal(err)
 		}
+	}
+	cfg.Views = views.NewRecorder(&models.Statistics, logger)
+	api := rest.InitializeAPI(cfg)
+
+	// workers get their own context, cancelled only once the server has
+	// stopped, so the views recorded by the last requests are still flushed
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	var workers sync.WaitGroup
+	for _, run := range []func(context.Context){
+		worker.NewPublisher(models, logger, publishPeriod).Run,
+		worker.NewTrending(models, logger, trendingPeriod).Run,
+		cfg.Views.Run,
+	} {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			run(workerCtx)
+		}()
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	serverErr := make(chan error, 1)
+	go func() {
+		logger.PrintInfo("starting server", map[string]string{
+			"addr": api.Addr,
+		})
+		serverErr <- api.ListenAndServe()
 	}()
+
+	exitCode := 0
+	select {
+	case err := <-serverErr:
+		logger.PrintError(err, nil)
+		exitCode = 1
+	case <-ctx.Done():
+		logger.PrintInfo("shutting down server", nil)
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		if err := api.Shutdown(shutdownCtx); err != nil {
+			logger.PrintError(err, nil)
+			exitCode = 1
+		}
+		cancel()
+	}
+	stop()
+
+	stopWorkers()
+	workers.Wait()
+	if err := db.Close(); err != nil {
+		logger.PrintError(err, nil)
+		exitCode = 1
+	}
+	logger.PrintInfo("stopped", nil)
+	os.Exit(exitCode)
+}
+
+// parsePeriod reads how often a worker runs. Tickers panic on periods that
+// aren't positive, so those are rejected here with the variable's name.
+func parsePeriod(name, value string) (time.Duration, error) {
+	period, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", name, err)
+	}
+	if period <= 0 {
+		return 0, fmt.Errorf("%s must be positive, got %s", name, value)
+	}
+	return period, nil
+}
+
+// bootstrapAdmin promotes the account behind ADMIN_EMAIL to admin, recording
+// it in role_changes with no changed_by. The account has to exist and be
+// activated, so whoever set the variable has proven they own the address;
+// until then startup only logs that it is waiting. It is a no-op once the
+// account is an admin, so the variable can stay set across restarts.
+func bootstrapAdmin(users *data.UserModel, logger *jsonlog.Logger, email string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user, err := users.GetByEmail(ctx, email)
+	if errors.Is(err, data.ErrRecordNotFound) {
+		logger.PrintInfo("admin bootstrap waiting for ADMIN_EMAIL to register", map[string]string{
+			"email": email,
+		})
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("ADMIN_EMAIL: %w", err)
+	}
+	if user.Role == data.RoleAdmin {
+		return nil
+	}
+	if !user.Activated {
+		logger.PrintInfo("admin bootstrap waiting for ADMIN_EMAIL to activate", map[string]string{
+			"email": email,
+		})
+		return nil
+	}
+
+	change, err := users.UpdateRole(ctx, &data.RoleChange{
+		UserID:  user.ID,
+		NewRole: data.RoleAdmin,
+		Reason:  "bootstrapped from ADMIN_EMAIL",
+	})
+	if err != nil {
+		return fmt.Errorf("ADMIN_EMAIL: %w", err)
+	}
+	logger.PrintInfo("user role changed", map[string]string{
+		"user_id":  change.UserID,
+		"old_role": change.OldRole,
+		"new_role": change.NewRole,
+		"reason":   change.Reason,
+	})
+	return nil
 }
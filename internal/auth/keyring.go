@@ -0,0 +1,179 @@
+// Package auth signs and verifies the API's JSON web tokens.
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"time"
+)
+
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+)
+
+var (
+	ErrUnknownKey = errors.New("token signed with an unknown key")
+	ErrRetiredKey = errors.New("token signed with a retired key")
+)
+
+// Key is one signing key. A key without a private half can only verify,
+// which is how a retired asymmetric key is usually kept around.
+type Key struct {
+	ID        string
+	Algorithm string
+	// RetiredAt is when the key stopped signing new tokens. Tokens it signed
+	// are still accepted for the keyring's rotation window after that.
+	RetiredAt *time.Time
+	signKey   any
+	verifyKey any
+}
+
+func (k *Key) method() jwt.SigningMethod {
+	switch k.Algorithm {
+	case AlgRS256:
+		return jwt.SigningMethodRS256
+	case AlgEdDSA:
+		return jwt.SigningMethodEdDSA
+	default:
+		return jwt.SigningMethodHS256
+	}
+}
+
+// NewHMACKey makes an HS256 key from a shared secret.
+func NewHMACKey(id string, secret []byte) (*Key, error) {
+	if len(secret) == 0 {
+		return nil, fmt.Errorf("HS256 key %q has an empty secret", id)
+	}
+	return &Key{ID: id, Algorithm: AlgHS256, signKey: secret, verifyKey: secret}, nil
+}
+
+// NewRSAKey makes an RS256 key. Pass a nil private key for a verify-only key.
+func NewRSAKey(id string, private *rsa.PrivateKey, public *rsa.PublicKey) *Key {
+	key := &Key{ID: id, Algorithm: AlgRS256, verifyKey: public}
+	if private != nil {
+		key.signKey = private
+		key.verifyKey = &private.PublicKey
+	}
+	return key
+}
+
+// NewEd25519Key makes an EdDSA key. Pass a nil private key for a verify-only
+// key.
+func NewEd25519Key(id string, private ed25519.PrivateKey, public ed25519.PublicKey) *Key {
+	key := &Key{ID: id, Algorithm: AlgEdDSA, verifyKey: public}
+	if private != nil {
+		key.signKey = private
+		key.verifyKey = private.Public()
+	}
+	return key
+}
+
+// Keyring signs with a single active key and verifies with any key it
+// holds, picked by the token's kid header.
+type Keyring struct {
+	active         *Key
+	keys           map[string]*Key
+	rotationWindow time.Duration
+	now            func() time.Time
+}
+
+// NewKeyring builds a keyring that signs with the key named activeID.
+// Retired keys keep verifying for rotationWindow after their RetiredAt,
+// which should be at least as long as the longest token lifetime.
+func NewKeyring(activeID string, rotationWindow time.Duration, keys ...*Key) (*Keyring, error) {
+	kr := &Keyring{
+		keys:           make(map[string]*Key, len(keys)),
+		rotationWindow: rotationWindow,
+		now:            time.Now,
+	}
+	for _, key := range keys {
+		if _, ok := kr.keys[key.ID]; ok {
+			return nil, fmt.Errorf("duplicate key id %q", key.ID)
+		}
+		kr.keys[key.ID] = key
+	}
+	active, ok := kr.keys[activeID]
+	if !ok {
+		return nil, fmt.Errorf("active key %q is not in the keyring", activeID)
+	}
+	if active.signKey == nil {
+		return nil, fmt.Errorf("active key %q has no private key", activeID)
+	}
+	if active.RetiredAt != nil {
+		return nil, fmt.Errorf("active key %q is retired", activeID)
+	}
+	kr.active = active
+	return kr, nil
+}
+
+// Sign issues a token for the payload that expires after ttl.
+func (kr *Keyring) Sign(payload map[string]string, ttl time.Duration) (string, error) {
+	now := kr.now()
+	claims := jwt.MapClaims{
+		"iat": now.Unix(),
+		"exp": now.Add(ttl).Unix(),
+	}
+	for key, value := range payload {
+		claims[key] = value
+	}
+	token := jwt.NewWithClaims(kr.active.method(), claims)
+	token.Header["kid"] = kr.active.ID
+	return token.SignedString(kr.active.signKey)
+}
+
+// Parse verifies tokenString and decodes it into claims.
+func (kr *Keyring) Parse(tokenString string, claims jwt.Claims) error {
+	token, err := jwt.ParseWithClaims(
+		tokenString,
+		claims,
+		kr.keyFunc,
+		jwt.WithValidMethods([]string{AlgHS256, AlgRS256, AlgEdDSA}),
+		jwt.WithTimeFunc(kr.now),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return err
+	}
+	if !token.Valid {
+		return errors.New("invalid token provided")
+	}
+	return nil
+}
+
+func (kr *Keyring) keyFunc(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := kr.keys[kid]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	// a token must be verified with the algorithm its key was made for, or
+	// an RSA public key could be passed off as an HMAC secret
+	if token.Method.Alg() != key.Algorithm {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	if !kr.verifies(key) {
+		return nil, ErrRetiredKey
+	}
+	return key.verifyKey, nil
+}
+
+func (kr *Keyring) verifies(key *Key) bool {
+	return key.RetiredAt == nil || kr.now().Before(key.RetiredAt.Add(kr.rotationWindow))
+}
+
+// publicKey is the half of key that may be shared, or nil for HMAC keys.
+func publicKey(key *Key) crypto.PublicKey {
+	switch k := key.verifyKey.(type) {
+	case *rsa.PublicKey:
+		return k
+	case ed25519.PublicKey:
+		return k
+	}
+	return nil
+}
@@ -0,0 +1,236 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"github.com/golang-jwt/jwt/v5"
+	"testing"
+	"time"
+)
+
+var keyringNow = time.Unix(1700000000, 0)
+
+func testKeys(t *testing.T) (hmacKey, rsaKey, edKey *Key) {
+	t.Helper()
+	hmacKey, err := NewHMACKey("hs", []byte("a-long-enough-shared-secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaPrivate, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edPrivate, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hmacKey, NewRSAKey("rs", rsaPrivate, nil), NewEd25519Key("ed", edPrivate, nil)
+}
+
+func fixedKeyring(t *testing.T, at time.Time, activeID string, window time.Duration, keys ...*Key) *Keyring {
+	t.Helper()
+	kr, err := NewKeyring(activeID, window, keys...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kr.now = func() time.Time { return at }
+	return kr
+}
+
+// signWith signs claims with key directly, bypassing the keyring's active
+// key, so tests can mint tokens for retired and foreign keys.
+func signWith(t *testing.T, key *Key, method jwt.SigningMethod, secret any) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, jwt.MapClaims{
+		"sub": "ada",
+		"iat": keyringNow.Unix(),
+		"exp": keyringNow.Add(24 * time.Hour).Unix(),
+	})
+	token.Header["kid"] = key.ID
+	signed, err := token.SignedString(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestKeyringSignAndParse(t *testing.T) {
+	hmacKey, rsaKey, edKey := testKeys(t)
+	tests := []struct {
+		name   string
+		active string
+	}{
+		{"HS256", "hs"},
+		{"RS256", "rs"},
+		{"EdDSA", "ed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kr := fixedKeyring(t, keyringNow, tt.active, time.Hour, hmacKey, rsaKey, edKey)
+			signed, err := kr.Sign(map[string]string{"sub": "ada"}, time.Hour)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			token, _, err := jwt.NewParser().ParseUnverified(signed, jwt.MapClaims{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if kid := token.Header["kid"]; kid != tt.active {
+				t.Errorf("got kid %v, want %s", kid, tt.active)
+			}
+			if alg := token.Method.Alg(); alg != tt.name {
+				t.Errorf("got alg %s, want %s", alg, tt.name)
+			}
+
+			claims := jwt.MapClaims{}
+			if err := kr.Parse(signed, claims); err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			if claims["sub"] != "ada" {
+				t.Errorf("got sub %v, want ada", claims["sub"])
+			}
+		})
+	}
+}
+
+func TestKeyringParseExpired(t *testing.T) {
+	hmacKey, _, _ := testKeys(t)
+	signed, err := fixedKeyring(t, keyringNow, "hs", time.Hour, hmacKey).Sign(nil, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	later := fixedKeyring(t, keyringNow.Add(2*time.Minute), "hs", time.Hour, hmacKey)
+	if err := later.Parse(signed, jwt.MapClaims{}); !errors.Is(err, jwt.ErrTokenExpired) {
+		t.Fatalf("got %v, want %v", err, jwt.ErrTokenExpired)
+	}
+}
+
+func TestKeyringRetiredKey(t *testing.T) {
+	window := 2 * time.Hour
+	retiredAt := keyringNow
+	tests := []struct {
+		name    string
+		at      time.Time
+		wantErr error
+	}{
+		{"just retired", retiredAt, nil},
+		{"inside the rotation window", retiredAt.Add(window - time.Second), nil},
+		{"at the end of the window", retiredAt.Add(window), ErrRetiredKey},
+		{"after the window", retiredAt.Add(window + time.Hour), ErrRetiredKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hmacKey, rsaKey, _ := testKeys(t)
+			rsaKey.RetiredAt = &retiredAt
+			signed := signWith(t, rsaKey, jwt.SigningMethodRS256, rsaKey.signKey)
+
+			kr := fixedKeyring(t, tt.at, "hs", window, hmacKey, rsaKey)
+			err := kr.Parse(signed, jwt.MapClaims{})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestKeyringRejectsUnknownKid(t *testing.T) {
+	hmacKey, rsaKey, edKey := testKeys(t)
+	kr := fixedKeyring(t, keyringNow, "hs", time.Hour, hmacKey, rsaKey)
+
+	tests := []struct {
+		name   string
+		signed string
+	}{
+		{"key not in the keyring", signWith(t, edKey, jwt.SigningMethodEdDSA, edKey.signKey)},
+		{"missing kid", func() string {
+			token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+				"exp": keyringNow.Add(time.Hour).Unix(),
+			})
+			signed, err := token.SignedString(hmacKey.signKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return signed
+		}()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := kr.Parse(tt.signed, jwt.MapClaims{})
+			if !errors.Is(err, ErrUnknownKey) {
+				t.Fatalf("got %v, want %v", err, ErrUnknownKey)
+			}
+		})
+	}
+}
+
+// An attacker who knows an asymmetric public key must not be able to mint
+// tokens by using it as an HS256 secret under that key's kid.
+func TestKeyringRejectsAlgorithmConfusion(t *testing.T) {
+	hmacKey, rsaKey, edKey := testKeys(t)
+	kr := fixedKeyring(t, keyringNow, "hs", time.Hour, hmacKey, rsaKey, edKey)
+
+	pemPublic := func(key *Key) []byte {
+		der, err := x509.MarshalPKIXPublicKey(publicKey(key))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	}
+	tests := []struct {
+		name   string
+		key    *Key
+		secret []byte
+	}{
+		{"RSA public key PEM", rsaKey, pemPublic(rsaKey)},
+		{"RSA public key DER", rsaKey, x509.MarshalPKCS1PublicKey(&rsaKey.signKey.(*rsa.PrivateKey).PublicKey)},
+		{"Ed25519 public key PEM", edKey, pemPublic(edKey)},
+		{"Ed25519 public key bytes", edKey, []byte(publicKey(edKey).(ed25519.PublicKey))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signed := signWith(t, tt.key, jwt.SigningMethodHS256, tt.secret)
+			// the keyfunc must refuse the token before any signature check
+			err := kr.Parse(signed, jwt.MapClaims{})
+			if !errors.Is(err, jwt.ErrTokenUnverifiable) {
+				t.Fatalf("got %v, want %v", err, jwt.ErrTokenUnverifiable)
+			}
+		})
+	}
+}
+
+func TestNewKeyring(t *testing.T) {
+	hmacKey, rsaKey, _ := testKeys(t)
+	verifyOnly := NewRSAKey("rs-public", nil, &rsaKey.signKey.(*rsa.PrivateKey).PublicKey)
+	retiredAt := keyringNow
+	retired, err := NewHMACKey("old", []byte("an-older-shared-secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	retired.RetiredAt = &retiredAt
+
+	tests := []struct {
+		name    string
+		active  string
+		keys    []*Key
+		wantErr bool
+	}{
+		{"active key present", "hs", []*Key{hmacKey, rsaKey}, false},
+		{"active key missing", "nope", []*Key{hmacKey}, true},
+		{"duplicate ids", "hs", []*Key{hmacKey, hmacKey}, true},
+		{"active key cannot sign", "rs-public", []*Key{verifyOnly}, true},
+		{"active key retired", "old", []*Key{retired}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewKeyring(tt.active, time.Hour, tt.keys...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got err %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"encoding/pem"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ParseKey builds a key from the contents of a key file. HS256 keys are the
+// raw secret; RS256 and EdDSA keys are PEM, either a private key for a key
+// that signs or a public key for one that only verifies.
+func ParseKey(id, algorithm string, contents []byte) (*Key, error) {
+	switch algorithm {
+	case AlgHS256:
+		return NewHMACKey(id, contents)
+	case AlgRS256, AlgEdDSA:
+	default:
+		return nil, fmt.Errorf("key %q has unsupported algorithm %q", id, algorithm)
+	}
+
+	block, _ := pem.Decode(contents)
+	if block == nil {
+		return nil, fmt.Errorf("key %q is not PEM encoded", id)
+	}
+	public := block.Type == "PUBLIC KEY" || block.Type == "RSA PUBLIC KEY"
+
+	switch {
+	case algorithm == AlgRS256 && public:
+		key, err := jwt.ParseRSAPublicKeyFromPEM(contents)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", id, err)
+		}
+		return NewRSAKey(id, nil, key), nil
+	case algorithm == AlgRS256:
+		key, err := jwt.ParseRSAPrivateKeyFromPEM(contents)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", id, err)
+		}
+		return NewRSAKey(id, key, nil), nil
+	case public:
+		key, err := jwt.ParseEdPublicKeyFromPEM(contents)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", id, err)
+		}
+		return NewEd25519Key(id, nil, key.(ed25519.PublicKey)), nil
+	default:
+		key, err := jwt.ParseEdPrivateKeyFromPEM(contents)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", id, err)
+		}
+		return NewEd25519Key(id, key.(ed25519.PrivateKey), nil), nil
+	}
+}
@@ -0,0 +1,164 @@
+package auth
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 seed from RFC 6238 Appendix B.
+var rfcSecret = base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func fixedTOTP(at time.Time) *TOTP {
+	t := NewTOTP("65ch")
+	t.Now = func() time.Time { return at }
+	return t
+}
+
+func TestTOTPCode(t *testing.T) {
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	}
+	for _, tt := range tests {
+		totp := fixedTOTP(time.Unix(tt.unix, 0))
+		totp.Digits = 8
+		got, err := totp.Code(rfcSecret, totp.Step(totp.Now()))
+		if err != nil {
+			t.Fatalf("T=%d: %v", tt.unix, err)
+		}
+		if got != tt.want {
+			t.Errorf("T=%d: got %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestTOTPValidate(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	current := fixedTOTP(now).Step(now)
+	tests := []struct {
+		name   string
+		step   int64
+		wantOK bool
+	}{
+		{"current step", current, true},
+		{"one step behind", current - 1, true},
+		{"one step ahead", current + 1, true},
+		{"two steps behind", current - 2, false},
+		{"two steps ahead", current + 2, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			totp := fixedTOTP(now)
+			code, err := totp.Code(rfcSecret, tt.step)
+			if err != nil {
+				t.Fatal(err)
+			}
+			step, ok := totp.Validate(rfcSecret, code)
+			if ok != tt.wantOK {
+				t.Fatalf("got ok %v, want %v", ok, tt.wantOK)
+			}
+			if ok && step != tt.step {
+				t.Errorf("got step %d, want %d", step, tt.step)
+			}
+		})
+	}
+}
+
+func TestTOTPValidateRejectsMalformedCodes(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	totp := fixedTOTP(now)
+	code, err := totp.Code(rfcSecret, totp.Step(now))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := totp.Validate(rfcSecret, code[:3]+" "+code[3:]); !ok {
+		t.Error("code with a space in it was rejected")
+	}
+	for _, bad := range []string{"", code[:5], code + "0", "abcdef"} {
+		if _, ok := totp.Validate(rfcSecret, bad); ok {
+			t.Errorf("%q was accepted", bad)
+		}
+	}
+	if _, ok := totp.Validate("not base32!", code); ok {
+		t.Error("code was accepted against an invalid secret")
+	}
+}
+
+func TestTOTPVerify(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	totp := fixedTOTP(now)
+	current := totp.Step(now)
+	tests := []struct {
+		name     string
+		step     int64
+		lastStep int64
+		wantOK   bool
+	}{
+		{"never used", current, 0, true},
+		{"later than the last step", current, current - 1, true},
+		{"replayed", current, current, false},
+		{"older than the last step", current - 1, current, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, err := totp.Code(rfcSecret, tt.step)
+			if err != nil {
+				t.Fatal(err)
+			}
+			step, ok := totp.Verify(rfcSecret, code, tt.lastStep)
+			if ok != tt.wantOK {
+				t.Fatalf("got ok %v, want %v", ok, tt.wantOK)
+			}
+			if ok && step != tt.step {
+				t.Errorf("got step %d, want %d", step, tt.step)
+			}
+		})
+	}
+}
+
+func TestHashRecoveryCode(t *testing.T) {
+	want := HashRecoveryCode("abcde-fghij")
+	tests := []string{
+		"abcde-fghij",
+		"ABCDE-FGHIJ",
+		"abcdefghij",
+		"abcde fghij",
+		"  AbCdE-fGhIj ",
+	}
+	for _, typed := range tests {
+		if got := HashRecoveryCode(typed); got != want {
+			t.Errorf("%q hashed differently from abcde-fghij", typed)
+		}
+	}
+	if HashRecoveryCode("abcde-fghik") == want {
+		t.Error("a different code hashed the same")
+	}
+}
+
+func TestGenerateRecoveryCodes(t *testing.T) {
+	codes, hashes, err := GenerateRecoveryCodes(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := make(map[string]bool)
+	for i, code := range codes {
+		if len(code) != 11 || code[5] != '-' {
+			t.Errorf("code %q is not formatted as xxxxx-xxxxx", code)
+		}
+		if hashes[i] != HashRecoveryCode(code) {
+			t.Errorf("hash %d doesn't match its code", i)
+		}
+		if seen[code] {
+			t.Errorf("code %q generated twice", code)
+		}
+		seen[code] = true
+	}
+}
@@ -8,21 +8,58 @@ import (
 )
 
 type Env struct {
-	DbUrl          string
-	Port           string
-	Env            string
-	DbMaxOpenConns int
-	DbMaxIdleConns int
-	DbMaxTimeout   string
-	JwtSecret      string
+	DbUrl                string
+	Port                 string
+	Env                  string
+	DbMaxOpenConns       int
+	DbMaxIdleConns       int
+	DbMaxTimeout         string
+	JwtSecret            string
+	JwtKeysFile          string
+	JwtRotationWindow    string
+	PublishPeriod        string
+	TrendingPeriod       string
+	ClientUrl            string
+	Mailer               string
+	MailDir              string
+	MailSender           string
+	SmtpHost             string
+	SmtpPort             int
+	SmtpUsername         string
+	SmtpPassword         string
+	RateLimitEnabled     bool
+	RateLimitAuth        int
+	RateLimitWrite       int
+	RateLimitRead        int
+	PasswordMinLength    int
+	PasswordMinScore     int
+	PasswordBreachCorpus string
+	// AdminEmail names the account promoted to admin at startup. Every
+	// signup is an author, so this is how a fresh install gets its first
+	// admin; later admins are assigned through the admin API.
+	AdminEmail string
 }
 
 const (
-	DefaultEnv          = "development"
-	DefaultPort         = "8080"
-	DefaultMaxTimeout   = "30s"
-	DefaultMaxOpenConns = 10
-	DefaultMaxIdleConns = 5
+	DefaultEnv               = "development"
+	DefaultPort              = "8080"
+	DefaultMaxTimeout        = "30s"
+	DefaultMaxOpenConns      = 10
+	DefaultMaxIdleConns      = 5
+	DefaultPublishPeriod     = "30s"
+	DefaultTrendingPeriod    = "10m"
+	DefaultJwtRotationWindow = "24h"
+	DefaultClientUrl         = "http://localhost:3000"
+	DefaultMailer            = "file"
+	DefaultMailDir           = "tmp/mail"
+	DefaultMailSender        = "65ch <no-reply@65ch.local>"
+	DefaultSmtpPort          = 587
+	// rate limits are requests per minute, per user or per IP
+	DefaultRateLimitAuth     = 20
+	DefaultRateLimitWrite    = 60
+	DefaultRateLimitRead     = 600
+	DefaultPasswordMinLength = 10
+	DefaultPasswordMinScore  = 3
 )
 
 func LoadEnvVariables() (Env, error) {
@@ -32,13 +69,33 @@ func LoadEnvVariables() (Env, error) {
 	}
 
 	e := Env{
-		DbUrl:          getEnv("DB_URL", ""),
-		Port:           getEnv("PORT", DefaultPort),
-		JwtSecret:      getEnv("JWT_SECRET", ""),
-		DbMaxTimeout:   getEnv("DB_MAX_TIMEOUT", DefaultMaxTimeout),
-		Env:            getEnv("ENV", DefaultEnv),
-		DbMaxOpenConns: getEnvAsInt("DB_MAX_OPEN_CONNS", DefaultMaxOpenConns),
-		DbMaxIdleConns: getEnvAsInt("DB_MAX_IDLE_CONNS", DefaultMaxIdleConns),
+		DbUrl:                getEnv("DB_URL", ""),
+		Port:                 getEnv("PORT", DefaultPort),
+		JwtSecret:            getEnv("JWT_SECRET", ""),
+		JwtKeysFile:          getEnv("JWT_KEYS_FILE", ""),
+		JwtRotationWindow:    getEnv("JWT_ROTATION_WINDOW", DefaultJwtRotationWindow),
+		DbMaxTimeout:         getEnv("DB_MAX_TIMEOUT", DefaultMaxTimeout),
+		Env:                  getEnv("ENV", DefaultEnv),
+		DbMaxOpenConns:       getEnvAsInt("DB_MAX_OPEN_CONNS", DefaultMaxOpenConns),
+		DbMaxIdleConns:       getEnvAsInt("DB_MAX_IDLE_CONNS", DefaultMaxIdleConns),
+		PublishPeriod:        getEnv("PUBLISH_PERIOD", DefaultPublishPeriod),
+		TrendingPeriod:       getEnv("TRENDING_PERIOD", DefaultTrendingPeriod),
+		ClientUrl:            getEnv("CLIENT_URL", DefaultClientUrl),
+		Mailer:               getEnv("MAILER", DefaultMailer),
+		MailDir:              getEnv("MAIL_DIR", DefaultMailDir),
+		MailSender:           getEnv("MAIL_SENDER", DefaultMailSender),
+		SmtpHost:             getEnv("SMTP_HOST", ""),
+		SmtpPort:             getEnvAsInt("SMTP_PORT", DefaultSmtpPort),
+		SmtpUsername:         getEnv("SMTP_USERNAME", ""),
+		SmtpPassword:         getEnv("SMTP_PASSWORD", ""),
+		RateLimitEnabled:     getEnvAsBool("RATE_LIMIT_ENABLED", true),
+		RateLimitAuth:        getEnvAsInt("RATE_LIMIT_AUTH", DefaultRateLimitAuth),
+		RateLimitWrite:       getEnvAsInt("RATE_LIMIT_WRITE", DefaultRateLimitWrite),
+		RateLimitRead:        getEnvAsInt("RATE_LIMIT_READ", DefaultRateLimitRead),
+		PasswordMinLength:    getEnvAsInt("PASSWORD_MIN_LENGTH", DefaultPasswordMinLength),
+		PasswordMinScore:     getEnvAsInt("PASSWORD_MIN_SCORE", DefaultPasswordMinScore),
+		PasswordBreachCorpus: getEnv("PASSWORD_BREACH_CORPUS", ""),
+		AdminEmail:           getEnv("ADMIN_EMAIL", ""),
 	}
 	return e, nil
 }
@@ -61,3 +118,15 @@ func getEnvAsInt(key string, fallback int) int {
 	}
 	return fallback
 }
+
+func getEnvAsBool(key string, fallback bool) bool {
+	if value, ok := os.LookupEnv(key); ok {
+		result, err := strconv.ParseBool(value)
+		if err != nil {
+			log.Printf("Error converting %s to boolean, using default value: %t", key, fallback)
+			return fallback
+		}
+		return result
+	}
+	return fallback
+}
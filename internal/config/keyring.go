@@ -0,0 +1,73 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/rx-rz/65ch/internal/auth"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// keyringFile is the shape of JWT_KEYS_FILE. Key files are resolved
+// relative to the keyring file.
+//
+//	{
+//	  "active": "2024-11",
+//	  "keys": [
+//	    {"kid": "2024-11", "alg": "EdDSA", "file": "2024-11.pem"},
+//	    {"kid": "2024-06", "alg": "RS256", "file": "2024-06.pub.pem", "retired_at": "2024-11-18T00:00:00Z"}
+//	  ]
+//	}
+type keyringFile struct {
+	Active string `json:"active"`
+	Keys   []struct {
+		ID        string     `json:"kid"`
+		Algorithm string     `json:"alg"`
+		File      string     `json:"file"`
+		RetiredAt *time.Time `json:"retired_at"`
+	} `json:"keys"`
+}
+
+// InitializeKeyring loads the token signing keys from JWT_KEYS_FILE. Without
+// one, tokens are signed with JWT_SECRET as a single HS256 key.
+func InitializeKeyring(envs Env) (*auth.Keyring, error) {
+	rotationWindow, err := time.ParseDuration(envs.JwtRotationWindow)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JWT_ROTATION_WINDOW: %w", err)
+	}
+	if envs.JwtKeysFile == "" {
+		if envs.JwtSecret == "" {
+			return nil, fmt.Errorf("either JWT_KEYS_FILE or JWT_SECRET must be set")
+		}
+		key, err := auth.NewHMACKey("default", []byte(envs.JwtSecret))
+		if err != nil {
+			return nil, err
+		}
+		return auth.NewKeyring(key.ID, rotationWindow, key)
+	}
+
+	contents, err := os.ReadFile(envs.JwtKeysFile)
+	if err != nil {
+		return nil, err
+	}
+	var file keyringFile
+	if err = json.Unmarshal(contents, &file); err != nil {
+		return nil, fmt.Errorf("invalid JWT_KEYS_FILE: %w", err)
+	}
+	dir := filepath.Dir(envs.JwtKeysFile)
+	keys := make([]*auth.Key, 0, len(file.Keys))
+	for _, entry := range file.Keys {
+		keyContents, err := os.ReadFile(filepath.Join(dir, entry.File))
+		if err != nil {
+			return nil, err
+		}
+		key, err := auth.ParseKey(entry.ID, entry.Algorithm, keyContents)
+		if err != nil {
+			return nil, err
+		}
+		key.RetiredAt = entry.RetiredAt
+		keys = append(keys, key)
+	}
+	return auth.NewKeyring(file.Active, rotationWindow, keys...)
+}
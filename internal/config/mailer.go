@@ -0,0 +1,22 @@
+package config
+
+import (
+	"fmt"
+	"github.com/rx-rz/65ch/internal/mailer"
+)
+
+// InitializeMailer picks the mail transport named by MAILER: "smtp" for a
+// real relay, or "file" to drop .eml files into MAIL_DIR during development.
+func InitializeMailer(envs Env) (mailer.Mailer, error) {
+	switch envs.Mailer {
+	case "smtp":
+		if envs.SmtpHost == "" {
+			return nil, fmt.Errorf("SMTP_HOST must be set when MAILER is smtp")
+		}
+		return mailer.NewSMTPMailer(envs.SmtpHost, envs.SmtpPort, envs.SmtpUsername, envs.SmtpPassword, envs.MailSender)
+	case "file":
+		return mailer.NewFileMailer(envs.MailDir, envs.MailSender)
+	default:
+		return nil, fmt.Errorf("unknown MAILER %q, expected smtp or file", envs.Mailer)
+	}
+}
@@ -2,14 +2,35 @@ package config
 
 import (
 	"database/sql"
+	"github.com/rx-rz/65ch/internal/auth"
 	"github.com/rx-rz/65ch/internal/jsonlog"
+	"github.com/rx-rz/65ch/internal/lockout"
+	"github.com/rx-rz/65ch/internal/mailer"
+	"github.com/rx-rz/65ch/internal/password"
+	"github.com/rx-rz/65ch/internal/ratelimit"
+	"github.com/rx-rz/65ch/internal/views"
 )
 
 type Config struct {
-	DB     *sql.DB
-	Logger *jsonlog.Logger
+	DB      *sql.DB
+	Logger  *jsonlog.Logger
+	Mailer  mailer.Mailer
+	Keyring *auth.Keyring
+	Env     Env
+	// AttemptStore tracks failed logins. Left nil, the API keeps them in
+	// memory, which is only correct for a single instance.
+	AttemptStore lockout.Store
+	// RateLimitStore holds the request rate limiter's buckets, in memory
+	// when left nil.
+	RateLimitStore ratelimit.Store
+	// PasswordPolicy judges new passwords, falling back to the default
+	// policy without a breach check when nil.
+	PasswordPolicy *password.Policy
+	// Views buffers article views for the API. It is required, and whoever
+	// runs the API also runs it, so the buffer is flushed before shutdown.
+	Views *views.Recorder
 }
 
-func New(db *sql.DB, logger *jsonlog.Logger) *Config {
-	return &Config{db, logger}
+func New(db *sql.DB, logger *jsonlog.Logger, mailer mailer.Mailer, keyring *auth.Keyring, env Env) *Config {
+	return &Config{DB: db, Logger: logger, Mailer: mailer, Keyring: keyring, Env: env}
 }
@@ -0,0 +1,21 @@
+package config
+
+import (
+	"fmt"
+	"github.com/rx-rz/65ch/internal/password"
+)
+
+// InitializePasswordPolicy builds the password policy from the environment.
+// Breached passwords are only rejected when PASSWORD_BREACH_CORPUS points
+// at an offline copy of the corpus.
+func InitializePasswordPolicy(envs Env) (*password.Policy, error) {
+	policy := &password.Policy{MinLength: envs.PasswordMinLength, MinScore: envs.PasswordMinScore}
+	if envs.PasswordBreachCorpus != "" {
+		breaches, err := password.NewBreachCorpus(envs.PasswordBreachCorpus)
+		if err != nil {
+			return nil, fmt.Errorf("invalid PASSWORD_BREACH_CORPUS: %w", err)
+		}
+		policy.Breaches = breaches
+	}
+	return policy, nil
+}
@@ -0,0 +1,148 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"github.com/rx-rz/65ch/internal/utils"
+	"time"
+)
+
+type ActivationToken struct {
+	ID         int       `json:"id"`
+	UserID     string    `json:"user_id"`
+	Token      string    `json:"-"`
+	Expiration time.Time `json:"expiration"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type ActivationTokenModel struct {
+	DB *sql.DB
+}
+
+// Create replaces any outstanding activation token for the user with a new
+// one. Only the token's hash is stored; the returned ActivationToken carries
+// the plaintext so it can be emailed.
+func (m ActivationTokenModel) Create(ctx context.Context, userID string, ttl time.Duration) (*ActivationToken, error) {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, DetermineDBError(err, "activationtoken_create")
+	}
+	defer tx.Rollback()
+
+	activationToken, err := replaceActivationToken(ctx, tx, userID, ttl)
+	if err != nil {
+		return nil, DetermineDBError(err, "activationtoken_create")
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, DetermineDBError(err, "activationtoken_create")
+	}
+	return activationToken, nil
+}
+
+func replaceActivationToken(ctx context.Context, tx *sql.Tx, userID string, ttl time.Duration) (*ActivationToken, error) {
+	token, hash, err := utils.GenerateSecureToken()
+	if err != nil {
+		return nil, err
+	}
+
+	const deleteQuery = `
+	DELETE FROM activation_tokens
+	WHERE user_id = $1
+	`
+	if _, err = tx.ExecContext(ctx, deleteQuery, userID); err != nil {
+		return nil, err
+	}
+
+	const query = `
+	INSERT INTO activation_tokens (user_id, token_hash, expiration)
+	VALUES ($1, $2, $3)
+	RETURNING id, user_id, expiration, created_at
+	`
+	activationToken := &ActivationToken{Token: token}
+	err = tx.QueryRowContext(
+		ctx,
+		query,
+		userID,
+		hash,
+		time.Now().Add(ttl).UTC(),
+	).Scan(
+		&activationToken.ID,
+		&activationToken.UserID,
+		&activationToken.Expiration,
+		&activationToken.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return activationToken, nil
+}
+
+func (m ActivationTokenModel) GetLatestForUser(ctx context.Context, userID string) (*ActivationToken, error) {
+	const query = `
+	SELECT id, user_id, expiration, created_at
+	FROM activation_tokens
+	WHERE user_id = $1
+	ORDER BY created_at DESC
+	LIMIT 1
+	`
+	activationToken := &ActivationToken{}
+	err := m.DB.QueryRowContext(
+		ctx,
+		query,
+		userID,
+	).Scan(
+		&activationToken.ID,
+		&activationToken.UserID,
+		&activationToken.Expiration,
+		&activationToken.CreatedAt,
+	)
+	if err != nil {
+		return nil, DetermineDBError(err, "activationtoken_getlatestforuser")
+	}
+	return activationToken, nil
+}
+
+// Activate spends a plaintext activation token and marks its user activated.
+// Expired and unknown tokens both come back as ErrRecordNotFound.
+func (m ActivationTokenModel) Activate(ctx context.Context, token string) (*ModifiedData, error) {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, DetermineDBError(err, "activationtoken_activate")
+	}
+	defer tx.Rollback()
+
+	const consumeQuery = `
+	DELETE FROM activation_tokens
+	WHERE token_hash = $1 AND expiration > now()
+	RETURNING user_id
+	`
+	var userID string
+	err = tx.QueryRowContext(ctx, consumeQuery, utils.HashToken(token)).Scan(&userID)
+	if err != nil {
+		return nil, DetermineDBError(err, "activationtoken_activate")
+	}
+
+	const activateQuery = `
+	UPDATE users
+	SET activated = true, updated_at = now(), version = version + 1
+	WHERE id = $1
+	RETURNING id, updated_at
+	`
+	data := &ModifiedData{}
+	err = tx.QueryRowContext(ctx, activateQuery, userID).Scan(&data.ID, &data.Timestamp)
+	if err != nil {
+		return nil, DetermineDBError(err, "activationtoken_activate")
+	}
+
+	const cleanupQuery = `
+	DELETE FROM activation_tokens
+	WHERE user_id = $1
+	`
+	if _, err = tx.ExecContext(ctx, cleanupQuery, userID); err != nil {
+		return nil, DetermineDBError(err, "activationtoken_activate")
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, DetermineDBError(err, "activationtoken_activate")
+	}
+	return data, nil
+}
@@ -0,0 +1,110 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+type ArticleRevision struct {
+	ID        int       `json:"id"`
+	ArticleID string    `json:"article_id"`
+	Revision  int       `json:"revision"`
+	Title     string    `json:"title"`
+	Content   string    `json:"content,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ArticleRevisionModel struct {
+	DB *sql.DB
+}
+
+// snapshot copies the article's current title and content into a new
+// revision before an update overwrites them. It runs on the caller's
+// transaction and does nothing when the incoming title and content match
+// what is already stored.
+func (m *ArticleRevisionModel) snapshot(ctx context.Context, tx *sql.Tx, articleID, title, content string) error {
+	const lockQuery = `
+	SELECT id FROM articles
+	WHERE id = $1
+	FOR UPDATE
+	`
+	var id string
+	err := tx.QueryRowContext(ctx, lockQuery, articleID).Scan(&id)
+	if err != nil {
+		return err
+	}
+
+	const query = `
+	INSERT INTO article_revisions (article_id, revision, title, content)
+	SELECT a.id,
+		COALESCE((SELECT max(revision) FROM article_revisions WHERE article_id = a.id), 0) + 1,
+		a.title,
+		a.content
+	FROM articles a
+	WHERE a.id = $1
+	AND (a.title IS DISTINCT FROM $2 OR a.content IS DISTINCT FROM $3)
+	`
+	_, err = tx.ExecContext(ctx, query, articleID, title, content)
+	return err
+}
+
+func (m *ArticleRevisionModel) GetAllForArticle(ctx context.Context, articleID string) ([]*ArticleRevision, error) {
+	const query = `
+	SELECT id, article_id, revision, title, created_at
+	FROM article_revisions
+	WHERE article_id = $1
+	ORDER BY revision DESC
+	`
+	rows, err := m.DB.QueryContext(ctx, query, articleID)
+	if err != nil {
+		return nil, DetermineDBError(err, "articlerevision_getallforarticle")
+	}
+	defer rows.Close()
+
+	revisions := []*ArticleRevision{}
+	for rows.Next() {
+		revision := &ArticleRevision{}
+		err = rows.Scan(
+			&revision.ID,
+			&revision.ArticleID,
+			&revision.Revision,
+			&revision.Title,
+			&revision.CreatedAt,
+		)
+		if err != nil {
+			return nil, DetermineDBError(err, "articlerevision_getallforarticle")
+		}
+		revisions = append(revisions, revision)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, DetermineDBError(err, "articlerevision_getallforarticle")
+	}
+	return revisions, nil
+}
+
+func (m *ArticleRevisionModel) Get(ctx context.Context, articleID string, revisionNumber int) (*ArticleRevision, error) {
+	const query = `
+	SELECT id, article_id, revision, title, content, created_at
+	FROM article_revisions
+	WHERE article_id = $1 AND revision = $2
+	`
+	revision := &ArticleRevision{}
+	err := m.DB.QueryRowContext(
+		ctx,
+		query,
+		articleID,
+		revisionNumber,
+	).Scan(
+		&revision.ID,
+		&revision.ArticleID,
+		&revision.Revision,
+		&revision.Title,
+		&revision.Content,
+		&revision.CreatedAt,
+	)
+	if err != nil {
+		return nil, DetermineDBError(err, "articlerevision_get")
+	}
+	return revision, nil
+}
@@ -0,0 +1,125 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/rx-rz/65ch/internal/utils"
+)
+
+// uniqueSlug picks the first free slug for title among the author's articles
+// and their slug history, appending -2, -3 and so on as needed. articleID is
+// the article being renamed, or empty for a new one, so that an article can
+// take back one of its own earlier slugs.
+func (m *ArticleModel) uniqueSlug(ctx context.Context, tx *sql.Tx, authorID, articleID, title string) (string, error) {
+	base := utils.Slugify(title)
+	if base == "" {
+		base = "untitled"
+	}
+
+	const query = `
+	SELECT slug FROM articles
+	WHERE author_id = $1 AND id::text <> $2 AND (slug = $3 OR slug LIKE $3 || '-%')
+	UNION
+	SELECT slug FROM article_slug_history
+	WHERE author_id = $1 AND article_id::text <> $2 AND (slug = $3 OR slug LIKE $3 || '-%')
+	`
+	rows, err := tx.QueryContext(ctx, query, authorID, articleID, base)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	taken := make(map[string]bool)
+	for rows.Next() {
+		var slug string
+		if err = rows.Scan(&slug); err != nil {
+			return "", err
+		}
+		taken[slug] = true
+	}
+	if err = rows.Err(); err != nil {
+		return "", err
+	}
+
+	slug := base
+	for n := 2; taken[slug]; n++ {
+		slug = fmt.Sprintf("%s-%d", base, n)
+	}
+	return slug, nil
+}
+
+// updateSlug regenerates the article's slug when its title changes and keeps
+// the old slug in article_slug_history so existing links can be redirected.
+func (m *ArticleModel) updateSlug(ctx context.Context, tx *sql.Tx, articleID, title string) error {
+	const currentQuery = `
+	SELECT author_id, title, slug
+	FROM articles
+	WHERE id = $1
+	`
+	var authorID, currentTitle, currentSlug string
+	err := tx.QueryRowContext(ctx, currentQuery, articleID).Scan(&authorID, &currentTitle, &currentSlug)
+	if err != nil {
+		return err
+	}
+	if currentTitle == title {
+		return nil
+	}
+	slug, err := m.uniqueSlug(ctx, tx, authorID, articleID, title)
+	if err != nil || slug == currentSlug {
+		return err
+	}
+
+	const historyQuery = `
+	INSERT INTO article_slug_history (article_id, author_id, slug)
+	VALUES ($1, $2, $3)
+	ON CONFLICT (author_id, slug) DO NOTHING
+	`
+	if _, err = tx.ExecContext(ctx, historyQuery, articleID, authorID, currentSlug); err != nil {
+		return err
+	}
+
+	const reclaimQuery = `
+	DELETE FROM article_slug_history
+	WHERE article_id = $1 AND slug = $2
+	`
+	if _, err = tx.ExecContext(ctx, reclaimQuery, articleID, slug); err != nil {
+		return err
+	}
+
+	const updateQuery = `
+	UPDATE articles
+	SET slug = $1
+	WHERE id = $2
+	`
+	_, err = tx.ExecContext(ctx, updateQuery, slug, articleID)
+	return err
+}
+
+// ResolveSlug finds the article an author's slug points to, whether the slug
+// is current or one the article used to have. It returns the article ID and
+// its current slug, which differs from slug when the caller should redirect.
+func (m *ArticleModel) ResolveSlug(ctx context.Context, username, slug string) (string, string, error) {
+	const query = `
+	SELECT id, slug FROM (
+		SELECT a.id, a.slug, 0 AS priority
+		FROM articles a
+		JOIN users u ON u.id = a.author_id
+		WHERE u.username = $1 AND a.slug = $2
+		UNION ALL
+		SELECT a.id, a.slug, 1 AS priority
+		FROM article_slug_history h
+		JOIN articles a ON a.id = h.article_id
+		JOIN users u ON u.id = h.author_id
+		WHERE u.username = $1 AND h.slug = $2
+	) matches
+	ORDER BY priority
+	LIMIT 1
+	`
+	var id, currentSlug string
+	err := m.DB.QueryRowContext(ctx, query, username, slug).Scan(&id, &currentSlug)
+	if err != nil {
+		return "", "", DetermineDBError(err, "article_resolveslug")
+	}
+	return id, currentSlug, nil
+}
@@ -1 +1,227 @@
 package data
+
+import (
+	"context"
+	"database/sql"
+	"github.com/lib/pq"
+	"time"
+)
+
+// ReadThreshold is how far down an article, in percent, a viewer has to
+// scroll for their view to count as a read.
+const ReadThreshold = 75
+
+// ArticleView is one view of an article by one viewer.
+type ArticleView struct {
+	ArticleID string
+	ViewerKey string
+	ViewedAt  time.Time
+}
+
+// ReadBeacon is how far a viewer got into an article and how long they
+// spent on it since the last beacon.
+type ReadBeacon struct {
+	ArticleID string
+	ViewerKey string
+	ReadDepth int
+	Seconds   int
+}
+
+type DailyArticleStatistics struct {
+	Day       string  `json:"day"`
+	Views     int     `json:"views"`
+	Reads     int     `json:"reads"`
+	ReadRatio float64 `json:"read_ratio"`
+	Likes     int     `json:"likes"`
+	Comments  int     `json:"comments"`
+}
+
+type ArticleStatistics struct {
+	ArticleID string                    `json:"article_id"`
+	Views     int                       `json:"views"`
+	Reads     int                       `json:"reads"`
+	ReadRatio float64                   `json:"read_ratio"`
+	Likes     int                       `json:"likes"`
+	Comments  int                       `json:"comments"`
+	Daily     []*DailyArticleStatistics `json:"daily"`
+}
+
+type ArticleStatisticsModel struct {
+	DB *sql.DB
+}
+
+// RecordViews stores a batch of views. A view is dropped when the same
+// viewer already has one for the article within dedupeWindow, so replicas
+// that each saw the viewer once don't count them twice.
+func (m *ArticleStatisticsModel) RecordViews(ctx context.Context, views []ArticleView, dedupeWindow time.Duration) error {
+	if len(views) == 0 {
+		return nil
+	}
+	articleIDs := make([]string, len(views))
+	viewerKeys := make([]string, len(views))
+	viewedAt := make([]string, len(views))
+	for i, view := range views {
+		articleIDs[i] = view.ArticleID
+		viewerKeys[i] = view.ViewerKey
+		viewedAt[i] = view.ViewedAt.UTC().Format(time.RFC3339Nano)
+	}
+	const query = `
+	WITH input AS (
+		SELECT DISTINCT ON (article_id, viewer_key) article_id, viewer_key, viewed_at
+		FROM unnest($1::uuid[], $2::text[], $3::timestamptz[]) AS i(article_id, viewer_key, viewed_at)
+		ORDER BY article_id, viewer_key, viewed_at
+	), inserted AS (
+		INSERT INTO article_views (article_id, viewer_key, viewed_at)
+		SELECT i.article_id, i.viewer_key, i.viewed_at
+		FROM input i
+		JOIN articles a ON a.id = i.article_id
+		WHERE NOT EXISTS (
+			SELECT 1 FROM article_views v
+			WHERE v.article_id = i.article_id
+			AND v.viewer_key = i.viewer_key
+			AND v.viewed_at > i.viewed_at - make_interval(secs => $4)
+		)
+		RETURNING article_id, viewed_at
+	)
+	INSERT INTO article_daily_stats (article_id, day, views)
+	SELECT article_id, (viewed_at AT TIME ZONE 'UTC')::date, count(*)
+	FROM inserted
+	GROUP BY 1, 2
+	ON CONFLICT (article_id, day) DO UPDATE SET views = article_daily_stats.views + EXCLUDED.views
+	`
+	_, err := m.DB.ExecContext(
+		ctx,
+		query,
+		pq.Array(articleIDs),
+		pq.Array(viewerKeys),
+		pq.Array(viewedAt),
+		dedupeWindow.Seconds(),
+	)
+	if err != nil {
+		return DetermineDBError(err, "articlestatistics_recordviews")
+	}
+	return nil
+}
+
+// RecordBeacons applies a batch of beacons to each viewer's latest view of
+// the article. A view that crosses ReadThreshold is counted as a read on
+// the day it was viewed.
+func (m *ArticleStatisticsModel) RecordBeacons(ctx context.Context, beacons []ReadBeacon) error {
+	if len(beacons) == 0 {
+		return nil
+	}
+	articleIDs := make([]string, len(beacons))
+	viewerKeys := make([]string, len(beacons))
+	depths := make([]int64, len(beacons))
+	seconds := make([]int64, len(beacons))
+	for i, beacon := range beacons {
+		articleIDs[i] = beacon.ArticleID
+		viewerKeys[i] = beacon.ViewerKey
+		depths[i] = int64(beacon.ReadDepth)
+		seconds[i] = int64(beacon.Seconds)
+	}
+	// read_at is set to this batch's timestamp, so the views it turns into
+	// reads are exactly those whose read_at matches it afterwards
+	const query = `
+	WITH input AS (
+		SELECT article_id, viewer_key, max(depth) AS depth, sum(seconds) AS seconds
+		FROM unnest($1::uuid[], $2::text[], $3::int[], $4::int[]) AS i(article_id, viewer_key, depth, seconds)
+		GROUP BY article_id, viewer_key
+	), latest AS (
+		SELECT DISTINCT ON (v.article_id, v.viewer_key) v.id, i.depth, i.seconds
+		FROM input i
+		JOIN article_views v ON v.article_id = i.article_id AND v.viewer_key = i.viewer_key
+		ORDER BY v.article_id, v.viewer_key, v.viewed_at DESC
+	), updated AS (
+		UPDATE article_views v
+		SET read_depth = GREATEST(v.read_depth, l.depth),
+			time_on_page = v.time_on_page + l.seconds,
+			read_at = CASE
+				WHEN v.read_at IS NULL AND GREATEST(v.read_depth, l.depth) >= $5 THEN $6::timestamptz
+				ELSE v.read_at
+			END
+		FROM latest l
+		WHERE v.id = l.id
+		RETURNING v.article_id, v.viewed_at, v.read_at
+	)
+	INSERT INTO article_daily_stats (article_id, day, reads)
+	SELECT article_id, (viewed_at AT TIME ZONE 'UTC')::date, count(*)
+	FROM updated
+	WHERE read_at = $6::timestamptz
+	GROUP BY 1, 2
+	ON CONFLICT (article_id, day) DO UPDATE SET reads = article_daily_stats.reads + EXCLUDED.reads
+	`
+	_, err := m.DB.ExecContext(
+		ctx,
+		query,
+		pq.Array(articleIDs),
+		pq.Array(viewerKeys),
+		pq.Array(depths),
+		pq.Array(seconds),
+		ReadThreshold,
+		time.Now().UTC(),
+	)
+	if err != nil {
+		return DetermineDBError(err, "articlestatistics_recordbeacons")
+	}
+	return nil
+}
+
+// Get returns the article's totals and a day-by-day breakdown for the last
+// days days, today included, with empty days filled with zeroes.
+func (m *ArticleStatisticsModel) Get(ctx context.Context, articleID string, days int) (*ArticleStatistics, error) {
+	const query = `
+	SELECT d::date::text,
+		COALESCE(s.views, 0),
+		COALESCE(s.reads, 0),
+		(SELECT count(*) FROM liked_articles
+			WHERE article_id = $1 AND (liked_at AT TIME ZONE 'UTC')::date = d::date),
+		(SELECT count(*) FROM comments
+			WHERE article_id = $1 AND deleted_at IS NULL AND (created_at AT TIME ZONE 'UTC')::date = d::date)
+	FROM generate_series(
+		(now() AT TIME ZONE 'UTC')::date - ($2::int - 1),
+		(now() AT TIME ZONE 'UTC')::date,
+		interval '1 day'
+	) AS d
+	LEFT JOIN article_daily_stats s ON s.article_id = $1 AND s.day = d::date
+	ORDER BY d
+	`
+	rows, err := m.DB.QueryContext(ctx, query, articleID, days)
+	if err != nil {
+		return nil, DetermineDBError(err, "articlestatistics_get")
+	}
+	defer rows.Close()
+
+	stats := &ArticleStatistics{ArticleID: articleID, Daily: []*DailyArticleStatistics{}}
+	for rows.Next() {
+		day := &DailyArticleStatistics{}
+		err = rows.Scan(
+			&day.Day,
+			&day.Views,
+			&day.Reads,
+			&day.Likes,
+			&day.Comments,
+		)
+		if err != nil {
+			return nil, DetermineDBError(err, "articlestatistics_get")
+		}
+		day.ReadRatio = readRatio(day.Reads, day.Views)
+		stats.Views += day.Views
+		stats.Reads += day.Reads
+		stats.Likes += day.Likes
+		stats.Comments += day.Comments
+		stats.Daily = append(stats.Daily, day)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, DetermineDBError(err, "articlestatistics_get")
+	}
+	stats.ReadRatio = readRatio(stats.Reads, stats.Views)
+	return stats, nil
+}
+
+func readRatio(reads, views int) float64 {
+	if views == 0 {
+		return 0
+	}
+	return float64(reads) / float64(views)
+}
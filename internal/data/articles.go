@@ -4,22 +4,52 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"github.com/lib/pq"
 	"time"
 )
 
 type Article struct {
-	ID          string    `json:"id"`
-	AuthorID    string    `json:"author_id"`
-	Title       string    `json:"title"`
-	Status      string    `json:"status"`
-	Category    string    `json:"category"`
-	TagIDs      []int     `json:"tag_ids,omitempty"`
-	CategoryID  int       `json:"category_id"`
-	Content     string    `json:"content"`
-	Tags        []string  `json:"tags,omitempty"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"last_updated_at"`
-	PublishedAt time.Time `json:"published_at,omitempty"`
+	ID          string     `json:"id"`
+	AuthorID    string     `json:"author_id"`
+	Title       string     `json:"title"`
+	Slug        string     `json:"slug"`
+	Status      string     `json:"status"`
+	Category    string     `json:"category"`
+	TagIDs      []int      `json:"tag_ids,omitempty"`
+	CategoryID  int        `json:"category_id"`
+	Content     string     `json:"content"`
+	Version     int        `json:"version"`
+	Tags        []string   `json:"tags,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"last_updated_at"`
+	PublishedAt *time.Time `json:"published_at,omitempty"`
+}
+
+type ArticleAuthor struct {
+	ID            string `json:"id"`
+	Username      string `json:"username"`
+	FirstName     string `json:"first_name"`
+	LastName      string `json:"last_name"`
+	Bio           string `json:"bio"`
+	ProfilePicUrl string `json:"profile_picture_url"`
+}
+
+// ArticleDetails is the composed view of a single article served to readers.
+type ArticleDetails struct {
+	Article
+	Author        ArticleAuthor `json:"author"`
+	LikeCount     int           `json:"like_count"`
+	CommentCount  int           `json:"comment_count"`
+	SaveCount     int           `json:"save_count"`
+	LikedByViewer bool          `json:"liked_by_viewer"`
+	SavedByViewer bool          `json:"saved_by_viewer"`
+}
+
+type ArticleSearchResult struct {
+	Article
+	Rank    float64 `json:"rank"`
+	Snippet string  `json:"snippet"`
 }
 
 type LikedArticle struct {
@@ -40,25 +70,39 @@ func (m *ArticleModel) Create(ctx context.Context, article *Article) (*Article,
 	defer tx.Rollback()
 
 	const query = `
-	INSERT INTO articles (author_id, title, content, status, published_at)
-	VALUES ($1, $2, $3, $4, $5)
-	RETURNING id, author_id, title, content, status
+	INSERT INTO articles (author_id, title, slug, content, status, category_id, published_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)
+	RETURNING id, author_id, title, slug, content, status
 	`
 	newArticle := &Article{}
-	publishTimestamp := time.Now().UTC()
+	// published articles default to going live now; drafts have no
+	// publish time until they are published or scheduled
+	publishTimestamp := article.PublishedAt
+	if publishTimestamp == nil && article.Status == "published" {
+		now := time.Now().UTC()
+		publishTimestamp = &now
+	}
+
+	slug, err := m.uniqueSlug(ctx, tx, article.AuthorID, "", article.Title)
+	if err != nil {
+		return nil, DetermineDBError(err, "article_generateslug")
+	}
 
 	err = tx.QueryRowContext(
 		ctx,
 		query,
 		article.AuthorID,
 		article.Title,
+		slug,
 		article.Content,
 		article.Status,
+		NilIfZero(article.CategoryID),
 		publishTimestamp,
 	).Scan(
 		&newArticle.ID,
 		&newArticle.AuthorID,
 		&newArticle.Title,
+		&newArticle.Slug,
 		&newArticle.Content,
 		&newArticle.Status,
 	)
@@ -107,7 +151,7 @@ func (m *ArticleModel) attachTags(ctx context.Context, tx *sql.Tx, articleID str
 
 func (m *ArticleModel) GetByID(ctx context.Context, id string) (*Article, error) {
 	q := `
-	SELECT id, author_id, title, content, created_at, updated_at, published_at 
+	SELECT id, author_id, title, slug, content, status, COALESCE(category_id, 0), version, created_at, updated_at, published_at 
 	FROM articles 
 	WHERE id = $1`
 
@@ -121,7 +165,11 @@ func (m *ArticleModel) GetByID(ctx context.Context, id string) (*Article, error)
 		&article.ID,
 		&article.AuthorID,
 		&article.Title,
+		&article.Slug,
 		&article.Content,
+		&article.Status,
+		&article.CategoryID,
+		&article.Version,
 		&article.CreatedAt,
 		&article.UpdatedAt,
 		&article.PublishedAt,
@@ -132,6 +180,222 @@ func (m *ArticleModel) GetByID(ctx context.Context, id string) (*Article, error)
 	return article, nil
 }
 
+// GetDetails returns the article along with its author, taxonomy and
+// engagement counts. viewerID may be empty for anonymous readers, in which
+// case the viewer flags are always false.
+func (m *ArticleModel) GetDetails(ctx context.Context, id, viewerID string) (*ArticleDetails, error) {
+	const query = `
+	SELECT a.id, a.author_id, a.title, a.slug, a.content, a.status, a.version,
+		COALESCE(a.category_id, 0), COALESCE(c.name, ''),
+		COALESCE((
+			SELECT array_agg(t.name ORDER BY t.name)
+			FROM article_tags atg
+			JOIN tags t ON t.id = atg.tag_id
+			WHERE atg.article_id = a.id
+		), '{}'),
+		a.created_at, a.updated_at, a.published_at,
+		u.id, u.username, u.first_name, u.last_name, u.bio, u.profile_picture_url,
+		(SELECT count(*) FROM liked_articles WHERE article_id = a.id),
+		(SELECT count(*) FROM comments WHERE article_id = a.id AND deleted_at IS NULL),
+		(SELECT count(*) FROM saved_articles WHERE article_id = a.id),
+		EXISTS (SELECT 1 FROM liked_articles WHERE article_id = a.id AND user_id::text = $2),
+		EXISTS (SELECT 1 FROM saved_articles WHERE article_id = a.id AND user_id::text = $2)
+	FROM articles a
+	JOIN users u ON u.id = a.author_id
+	LEFT JOIN categories c ON c.id = a.category_id
+	WHERE a.id = $1
+	`
+
+	details := &ArticleDetails{}
+	err := m.DB.QueryRowContext(
+		ctx,
+		query,
+		id,
+		viewerID,
+	).Scan(
+		&details.ID,
+		&details.AuthorID,
+		&details.Title,
+		&details.Slug,
+		&details.Content,
+		&details.Status,
+		&details.Version,
+		&details.CategoryID,
+		&details.Category,
+		pq.Array(&details.Tags),
+		&details.CreatedAt,
+		&details.UpdatedAt,
+		&details.PublishedAt,
+		&details.Author.ID,
+		&details.Author.Username,
+		&details.Author.FirstName,
+		&details.Author.LastName,
+		&details.Author.Bio,
+		&details.Author.ProfilePicUrl,
+		&details.LikeCount,
+		&details.CommentCount,
+		&details.SaveCount,
+		&details.LikedByViewer,
+		&details.SavedByViewer,
+	)
+	if err != nil {
+		return nil, DetermineDBError(err, "article_getdetails")
+	}
+	return details, nil
+}
+
+func (m *ArticleModel) GetAll(ctx context.Context, filters Filters) ([]*Article, Metadata, error) {
+	query := fmt.Sprintf(`
+	SELECT count(*) OVER(), a.id, a.author_id, a.title, a.slug, a.content, a.status,
+		COALESCE(a.category_id, 0), COALESCE(c.name, ''),
+		COALESCE(array_agg(t.name) FILTER (WHERE t.name IS NOT NULL), '{}'),
+		a.created_at, a.updated_at, a.published_at
+	FROM articles a
+	LEFT JOIN categories c ON c.id = a.category_id
+	LEFT JOIN article_tags atg ON atg.article_id = a.id
+	LEFT JOIN tags t ON t.id = atg.tag_id
+	WHERE (LOWER(c.name) = LOWER($1) OR $1 = '')
+	AND (a.status = $2 OR $2 = '')
+	AND (a.author_id::text = $3 OR $3 = '')
+	AND (a.title ILIKE '%%' || $4 || '%%' OR $4 = '')
+	GROUP BY a.id, c.name
+	HAVING (cardinality($5::text[]) = 0 OR array_agg(t.name) @> $5::text[])
+	ORDER BY a.%s %s, a.id ASC
+	LIMIT $6 OFFSET $7
+	`, filters.column(), filters.direction())
+
+	rows, err := m.DB.QueryContext(
+		ctx,
+		query,
+		filters.Category,
+		filters.Status,
+		filters.AuthorID,
+		filters.Search,
+		pq.Array(filters.Tags),
+		filters.limit(),
+		filters.offset(),
+	)
+	if err != nil {
+		return nil, Metadata{}, DetermineDBError(err, "article_getall")
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	articles := []*Article{}
+	for rows.Next() {
+		article := &Article{}
+		err = rows.Scan(
+			&totalRecords,
+			&article.ID,
+			&article.AuthorID,
+			&article.Title,
+			&article.Slug,
+			&article.Content,
+			&article.Status,
+			&article.CategoryID,
+			&article.Category,
+			pq.Array(&article.Tags),
+			&article.CreatedAt,
+			&article.UpdatedAt,
+			&article.PublishedAt,
+		)
+		if err != nil {
+			return nil, Metadata{}, DetermineDBError(err, "article_getall")
+		}
+		articles = append(articles, article)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, DetermineDBError(err, "article_getall")
+	}
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return articles, metadata, nil
+}
+
+// Search runs a full-text query over published articles. The query string in
+// filters.Search is parsed with websearch_to_tsquery, so quoted phrases, "or"
+// and "-term" all work the way readers expect from a search box.
+//
+// Snippets are safe to render as HTML: the content is escaped before the
+// matches are wrapped in <mark>, so the only markup left is ours.
+func (m *ArticleModel) Search(ctx context.Context, filters Filters) ([]*ArticleSearchResult, Metadata, error) {
+	query := fmt.Sprintf(`
+	SELECT total, id, author_id, title, slug, status, category_id, category, tags,
+		created_at, updated_at, published_at, rank,
+		ts_headline('english',
+			replace(replace(replace(replace(replace(content,
+				'&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&quot;'), '''', '&#39;'),
+			websearch_to_tsquery('english', $1),
+			'StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MaxWords=35, MinWords=15')
+	FROM (
+		SELECT count(*) OVER() AS total, a.id, a.author_id, a.title, a.slug, a.content, a.status,
+			COALESCE(a.category_id, 0) AS category_id, COALESCE(c.name, '') AS category,
+			COALESCE(array_agg(t.name) FILTER (WHERE t.name IS NOT NULL), '{}') AS tags,
+			a.created_at, a.updated_at, a.published_at,
+			ts_rank(a.search_vector, query) AS rank
+		FROM articles a
+		CROSS JOIN websearch_to_tsquery('english', $1) query
+		LEFT JOIN categories c ON c.id = a.category_id
+		LEFT JOIN article_tags atg ON atg.article_id = a.id
+		LEFT JOIN tags t ON t.id = atg.tag_id
+		WHERE a.search_vector @@ query
+		AND a.status = 'published'
+		AND (LOWER(c.name) = LOWER($2) OR $2 = '')
+		AND (a.author_id::text = $3 OR $3 = '')
+		GROUP BY a.id, c.name, query
+		HAVING (cardinality($4::text[]) = 0 OR array_agg(t.name) @> $4::text[])
+		ORDER BY %[1]s %[2]s, a.id ASC
+		LIMIT $5 OFFSET $6
+	) matches
+	ORDER BY %[1]s %[2]s, id ASC
+	`, filters.column(), filters.direction())
+
+	rows, err := m.DB.QueryContext(
+		ctx,
+		query,
+		filters.Search,
+		filters.Category,
+		filters.AuthorID,
+		pq.Array(filters.Tags),
+		filters.limit(),
+		filters.offset(),
+	)
+	if err != nil {
+		return nil, Metadata{}, DetermineDBError(err, "article_search")
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	results := []*ArticleSearchResult{}
+	for rows.Next() {
+		result := &ArticleSearchResult{}
+		err = rows.Scan(
+			&totalRecords,
+			&result.ID,
+			&result.AuthorID,
+			&result.Title,
+			&result.Slug,
+			&result.Status,
+			&result.CategoryID,
+			&result.Category,
+			pq.Array(&result.Tags),
+			&result.CreatedAt,
+			&result.UpdatedAt,
+			&result.PublishedAt,
+			&result.Rank,
+			&result.Snippet,
+		)
+		if err != nil {
+			return nil, Metadata{}, DetermineDBError(err, "article_search")
+		}
+		results = append(results, result)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, DetermineDBError(err, "article_search")
+	}
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return results, metadata, nil
+}
+
 func (m *ArticleModel) Update(ctx context.Context, article *Article) (*ModifiedData, error) {
 	tx, err := m.DB.BeginTx(ctx, nil)
 	if err != nil {
@@ -139,6 +403,16 @@ func (m *ArticleModel) Update(ctx context.Context, article *Article) (*ModifiedD
 	}
 	defer tx.Rollback()
 
+	revisions := ArticleRevisionModel{DB: m.DB}
+	err = revisions.snapshot(ctx, tx, article.ID, article.Title, article.Content)
+	if err != nil {
+		return nil, DetermineDBError(err, "article_snapshotrevision")
+	}
+	err = m.updateSlug(ctx, tx, article.ID, article.Title)
+	if err != nil {
+		return nil, DetermineDBError(err, "article_updateslug")
+	}
+
 	const query = `
 	UPDATE articles 
 	SET title = $1, 
@@ -146,9 +420,10 @@ func (m *ArticleModel) Update(ctx context.Context, article *Article) (*ModifiedD
 		status = $3,
 		category_id = $4,
 		updated_at = $5,
-		published_at = $6 
-	WHERE id = $7
-	RETURNING id
+		published_at = $6,
+		version = version + 1
+	WHERE id = $7 AND version = $8
+	RETURNING id, version
 	`
 
 	updateTimestamp := time.Now().UTC()
@@ -160,15 +435,26 @@ func (m *ArticleModel) Update(ctx context.Context, article *Article) (*ModifiedD
 		article.Title,
 		article.Content,
 		article.Status,
-		article.CategoryID,
+		NilIfZero(article.CategoryID),
 		updateTimestamp,
 		article.PublishedAt,
 		article.ID,
+		article.Version,
 	).Scan(
 		&data.ID,
+		&article.Version,
 	)
 
 	if err != nil {
+		// the row was locked and found by the revision snapshot above, so no
+		// rows here means the version has moved on since the caller read it
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &DBError{
+				Err:       ErrEditConflict,
+				Operation: "article_update",
+				Detail:    "article was modified by another request",
+			}
+		}
 		return nil, DetermineDBError(err, "article_update")
 	}
 	if len(article.TagIDs) > 0 {
@@ -185,6 +471,44 @@ func (m *ArticleModel) Update(ctx context.Context, article *Article) (*ModifiedD
 	return data, nil
 }
 
+// PublishDue flips up to limit scheduled articles whose publish time has
+// passed to published and returns their IDs. Rows are claimed with
+// FOR UPDATE SKIP LOCKED so concurrent publishers never pick the same article.
+func (m *ArticleModel) PublishDue(ctx context.Context, limit int) ([]string, error) {
+	const query = `
+	WITH due AS (
+		SELECT id FROM articles
+		WHERE status = 'scheduled' AND published_at <= now()
+		ORDER BY published_at
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	)
+	UPDATE articles a
+	SET status = 'published', updated_at = now()
+	FROM due
+	WHERE a.id = due.id
+	RETURNING a.id
+	`
+	rows, err := m.DB.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, DetermineDBError(err, "article_publishdue")
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err = rows.Scan(&id); err != nil {
+			return nil, DetermineDBError(err, "article_publishdue")
+		}
+		ids = append(ids, id)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, DetermineDBError(err, "article_publishdue")
+	}
+	return ids, nil
+}
+
 func (m *ArticleModel) Delete(id string) error {
 	q := `DELETE FROM articles WHERE id = $1`
 	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Second)
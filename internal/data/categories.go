@@ -12,6 +12,8 @@ type Category struct {
 	Name      string    `json:"name"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"last_updated_at"`
+	// FollowerCount is only filled in by GetAll.
+	FollowerCount int `json:"follower_count"`
 }
 
 type CategoryModel struct {
@@ -34,7 +36,8 @@ func (m CategoryModel) Create(name string) (*Category, error) {
 
 func (m CategoryModel) GetAll() ([]*Category, error) {
 	q := `
-	SELECT id, name FROM categories	ORDER BY created_at DESC 
+	SELECT c.id, c.name, (SELECT count(*) FROM category_follows WHERE category_id = c.id)
+	FROM categories c ORDER BY c.created_at DESC
 	`
 	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Second)
 	defer cancel()
@@ -46,7 +49,7 @@ func (m CategoryModel) GetAll() ([]*Category, error) {
 	var categories []*Category
 	for rows.Next() {
 		var c Category
-		err := rows.Scan(&c.ID, &c.Name)
+		err := rows.Scan(&c.ID, &c.Name, &c.FollowerCount)
 		if err != nil {
 			return nil, DetermineDBError(err, "category_getall")
 		}
@@ -3,15 +3,28 @@ package data
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
 	"time"
 )
 
+// DeletedCommentContent replaces the body of a comment that was deleted while
+// it still had replies.
+const DeletedCommentContent = "[deleted]"
+
 type Comment struct {
-	ID        string    `json:"id"`
-	UserID    string    `json:"user_id"`
-	ArticleID string    `json:"article_id"`
-	Content   string    `json:"content"`
-	CreatedAt time.Time `json:"created_at"`
+	ID        string       `json:"id"`
+	UserID    string       `json:"user_id,omitempty"`
+	ArticleID string       `json:"article_id"`
+	ParentID  *string      `json:"parent_id"`
+	Content   string       `json:"content"`
+	Author    *UserSummary `json:"author,omitempty"`
+	Depth     int          `json:"depth"`
+	Deleted   bool         `json:"deleted"`
+	CreatedAt time.Time    `json:"created_at"`
+	EditedAt  *time.Time   `json:"edited_at"`
+	Replies   []*Comment   `json:"replies,omitempty"`
 }
 
 type CommentModel struct {
@@ -20,9 +33,9 @@ type CommentModel struct {
 
 func (m *CommentModel) Create(ctx context.Context, comment *Comment) (*Comment, error) {
 	const query = `
-	INSERT INTO comments (user_id, article_id, content)
-	VALUES ($1, $2, $3)
-	RETURNING id, user_id, article_id, content, created_at
+	INSERT INTO comments (user_id, article_id, parent_id, content)
+	VALUES ($1, $2, $3, $4)
+	RETURNING id, user_id, article_id, parent_id, content, created_at
 	`
 	newComment := &Comment{}
 	err := m.DB.QueryRowContext(
@@ -30,11 +43,13 @@ func (m *CommentModel) Create(ctx context.Context, comment *Comment) (*Comment,
 		query,
 		comment.UserID,
 		comment.ArticleID,
+		comment.ParentID,
 		comment.Content,
 	).Scan(
 		&newComment.ID,
 		&newComment.UserID,
 		&newComment.ArticleID,
+		&newComment.ParentID,
 		&newComment.Content,
 		&newComment.CreatedAt,
 	)
@@ -44,21 +59,285 @@ func (m *CommentModel) Create(ctx context.Context, comment *Comment) (*Comment,
 	return newComment, nil
 }
 
-func (m *CommentModel) Delete(ctx context.Context, id string) (*ModifiedData, error) {
+func (m *CommentModel) GetByID(ctx context.Context, id string) (*Comment, error) {
 	const query = `
-	DELETE FROM comments 
+	SELECT id, user_id, article_id, parent_id, content, deleted_at IS NOT NULL, created_at, edited_at
+	FROM comments
 	WHERE id = $1
-	RETURNING id
 	`
-	data := &ModifiedData{}
+	comment := &Comment{}
+	err := m.DB.QueryRowContext(
+		ctx,
+		query,
+		id,
+	).Scan(
+		&comment.ID,
+		&comment.UserID,
+		&comment.ArticleID,
+		&comment.ParentID,
+		&comment.Content,
+		&comment.Deleted,
+		&comment.CreatedAt,
+		&comment.EditedAt,
+	)
+	if err != nil {
+		return nil, DetermineDBError(err, "comment_getbyid")
+	}
+	return comment, nil
+}
+
+// ListThreads returns a page of top-level comments on an article, each with
+// its full reply tree. Pagination and sorting apply to the top-level comments
+// only; replies are always ordered oldest first. Sort is one of "oldest",
+// "newest" or "top", where top ranks threads by how many replies they drew.
+func (m *CommentModel) ListThreads(ctx context.Context, articleID string, filters Filters) ([]*Comment, Metadata, error) {
+	var orderBy string
+	switch filters.Sort {
+	case "newest":
+		orderBy = "r.created_at DESC"
+	case "top":
+		orderBy = "COALESCE(s.replies, 0) DESC, r.created_at ASC"
+	default:
+		orderBy = "r.created_at ASC"
+	}
+
+	rootsQuery := fmt.Sprintf(`
+	WITH RECURSIVE thread AS (
+		SELECT id, id AS root_id
+		FROM comments
+		WHERE article_id = $1 AND parent_id IS NULL
+		UNION ALL
+		SELECT c.id, t.root_id
+		FROM comments c
+		JOIN thread t ON c.parent_id = t.id
+	)
+	SELECT count(*) OVER(), r.id
+	FROM comments r
+	LEFT JOIN (
+		SELECT root_id, count(*) - 1 AS replies
+		FROM thread
+		GROUP BY root_id
+	) s ON s.root_id = r.id
+	WHERE r.article_id = $1 AND r.parent_id IS NULL
+	ORDER BY %s, r.id ASC
+	LIMIT $2 OFFSET $3
+	`, orderBy)
+
+	rows, err := m.DB.QueryContext(ctx, rootsQuery, articleID, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, DetermineDBError(err, "comment_listthreads")
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	var rootIDs []string
+	for rows.Next() {
+		var id string
+		if err = rows.Scan(&totalRecords, &id); err != nil {
+			return nil, Metadata{}, DetermineDBError(err, "comment_listthreads")
+		}
+		rootIDs = append(rootIDs, id)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, DetermineDBError(err, "comment_listthreads")
+	}
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	if len(rootIDs) == 0 {
+		return []*Comment{}, metadata, nil
+	}
+
+	comments, err := m.getThreads(ctx, rootIDs)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	return buildCommentTree(rootIDs, comments), metadata, nil
+}
+
+func (m *CommentModel) getThreads(ctx context.Context, rootIDs []string) ([]*Comment, error) {
+	const query = `
+	WITH RECURSIVE thread AS (
+		SELECT c.*, 0 AS depth
+		FROM comments c
+		WHERE c.id::text = ANY($1)
+		UNION ALL
+		SELECT c.*, t.depth + 1
+		FROM comments c
+		JOIN thread t ON c.parent_id = t.id
+	)
+	SELECT t.id, t.user_id, t.article_id, t.parent_id, t.content, t.depth,
+		t.deleted_at IS NOT NULL, t.created_at, t.edited_at,
+		u.username, u.first_name, u.last_name, u.profile_picture_url
+	FROM thread t
+	JOIN users u ON u.id = t.user_id
+	ORDER BY t.created_at ASC, t.id ASC
+	`
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(rootIDs))
+	if err != nil {
+		return nil, DetermineDBError(err, "comment_getthreads")
+	}
+	defer rows.Close()
+
+	var comments []*Comment
+	for rows.Next() {
+		comment := &Comment{Author: &UserSummary{}}
+		err = rows.Scan(
+			&comment.ID,
+			&comment.UserID,
+			&comment.ArticleID,
+			&comment.ParentID,
+			&comment.Content,
+			&comment.Depth,
+			&comment.Deleted,
+			&comment.CreatedAt,
+			&comment.EditedAt,
+			&comment.Author.Username,
+			&comment.Author.FirstName,
+			&comment.Author.LastName,
+			&comment.Author.ProfilePicUrl,
+		)
+		if err != nil {
+			return nil, DetermineDBError(err, "comment_getthreads")
+		}
+		if comment.Deleted {
+			comment.UserID = ""
+			comment.Author = nil
+			comment.Content = DeletedCommentContent
+		} else {
+			comment.Author.ID = comment.UserID
+		}
+		comments = append(comments, comment)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, DetermineDBError(err, "comment_getthreads")
+	}
+	return comments, nil
+}
+
+// buildCommentTree nests comments (ordered oldest first) under their parents
+// and returns the roots in the order given by rootIDs.
+func buildCommentTree(rootIDs []string, comments []*Comment) []*Comment {
+	byID := make(map[string]*Comment, len(comments))
+	for _, comment := range comments {
+		byID[comment.ID] = comment
+	}
+	for _, comment := range comments {
+		if comment.ParentID == nil {
+			continue
+		}
+		if parent, ok := byID[*comment.ParentID]; ok {
+			parent.Replies = append(parent.Replies, comment)
+		}
+	}
+	roots := make([]*Comment, 0, len(rootIDs))
+	for _, id := range rootIDs {
+		if root, ok := byID[id]; ok {
+			roots = append(roots, root)
+		}
+	}
+	return roots
+}
+
+// FlattenCommentTree walks threads depth first and returns every comment in
+// reading order with its Replies cleared, relying on Depth for indentation.
+func FlattenCommentTree(roots []*Comment) []*Comment {
+	flat := []*Comment{}
+	var walk func(comments []*Comment)
+	walk = func(comments []*Comment) {
+		for _, comment := range comments {
+			replies := comment.Replies
+			comment.Replies = nil
+			flat = append(flat, comment)
+			walk(replies)
+		}
+	}
+	walk(roots)
+	return flat
+}
+
+func (m *CommentModel) UpdateContent(ctx context.Context, id, content string) (*Comment, error) {
+	const query = `
+	UPDATE comments
+	SET content = $1, edited_at = now()
+	WHERE id = $2 AND deleted_at IS NULL
+	RETURNING id, user_id, article_id, parent_id, content, created_at, edited_at
+	`
+	comment := &Comment{}
 	err := m.DB.QueryRowContext(
 		ctx,
 		query,
+		content,
 		id,
 	).Scan(
-		&data.ID,
+		&comment.ID,
+		&comment.UserID,
+		&comment.ArticleID,
+		&comment.ParentID,
+		&comment.Content,
+		&comment.CreatedAt,
+		&comment.EditedAt,
 	)
 	if err != nil {
+		return nil, DetermineDBError(err, "comment_updatecontent")
+	}
+	return comment, nil
+}
+
+// Delete removes a comment. A comment that still has replies is only blanked
+// out and marked deleted so the thread beneath it survives; one without
+// replies is removed outright, along with any deleted ancestors it was the
+// last reply to.
+func (m *CommentModel) Delete(ctx context.Context, id string) (*ModifiedData, error) {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, DetermineDBError(err, "comment_delete")
+	}
+	defer tx.Rollback()
+
+	const softDeleteQuery = `
+	UPDATE comments
+	SET content = '', deleted_at = now()
+	WHERE id = $1 AND EXISTS (SELECT 1 FROM comments WHERE parent_id = $1)
+	RETURNING id
+	`
+	data := &ModifiedData{}
+	err = tx.QueryRowContext(ctx, softDeleteQuery, id).Scan(&data.ID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, DetermineDBError(err, "comment_delete")
+	}
+
+	if errors.Is(err, sql.ErrNoRows) {
+		const query = `
+		DELETE FROM comments
+		WHERE id = $1
+		RETURNING id, parent_id
+		`
+		var parentID *string
+		err = tx.QueryRowContext(ctx, query, id).Scan(&data.ID, &parentID)
+		if err != nil {
+			return nil, DetermineDBError(err, "comment_delete")
+		}
+
+		const pruneQuery = `
+		DELETE FROM comments
+		WHERE id = $1
+		AND deleted_at IS NOT NULL
+		AND NOT EXISTS (SELECT 1 FROM comments WHERE parent_id = $1)
+		RETURNING parent_id
+		`
+		for parentID != nil {
+			var next *string
+			err = tx.QueryRowContext(ctx, pruneQuery, *parentID).Scan(&next)
+			if errors.Is(err, sql.ErrNoRows) {
+				break
+			}
+			if err != nil {
+				return nil, DetermineDBError(err, "comment_delete")
+			}
+			parentID = next
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
 		return nil, DetermineDBError(err, "comment_delete")
 	}
 	data.Timestamp = time.Now().UTC()
@@ -0,0 +1,128 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"github.com/rx-rz/65ch/internal/utils"
+	"time"
+)
+
+type EmailChangeToken struct {
+	ID         int       `json:"id"`
+	UserID     string    `json:"user_id"`
+	NewEmail   string    `json:"new_email"`
+	Token      string    `json:"-"`
+	Expiration time.Time `json:"expiration"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// EmailChange is a confirmed change of address.
+type EmailChange struct {
+	UserID    string    `json:"user_id"`
+	OldEmail  string    `json:"old_email"`
+	NewEmail  string    `json:"new_email"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+type EmailChangeTokenModel struct {
+	DB *sql.DB
+}
+
+// Create replaces any pending email change for the user with one to
+// newEmail. Only the token's hash is stored; the returned EmailChangeToken
+// carries the plaintext so it can be emailed to the new address.
+func (m EmailChangeTokenModel) Create(ctx context.Context, userID, newEmail string, ttl time.Duration) (*EmailChangeToken, error) {
+	token, hash, err := utils.GenerateSecureToken()
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, DetermineDBError(err, "emailchangetoken_create")
+	}
+	defer tx.Rollback()
+
+	const deleteQuery = `
+	DELETE FROM email_change_tokens
+	WHERE user_id = $1
+	`
+	if _, err = tx.ExecContext(ctx, deleteQuery, userID); err != nil {
+		return nil, DetermineDBError(err, "emailchangetoken_create")
+	}
+
+	const query = `
+	INSERT INTO email_change_tokens (user_id, new_email, token_hash, expiration)
+	VALUES ($1, $2, $3, $4)
+	RETURNING id, user_id, new_email, expiration, created_at
+	`
+	changeToken := &EmailChangeToken{Token: token}
+	err = tx.QueryRowContext(
+		ctx,
+		query,
+		userID,
+		newEmail,
+		hash,
+		time.Now().Add(ttl).UTC(),
+	).Scan(
+		&changeToken.ID,
+		&changeToken.UserID,
+		&changeToken.NewEmail,
+		&changeToken.Expiration,
+		&changeToken.CreatedAt,
+	)
+	if err != nil {
+		return nil, DetermineDBError(err, "emailchangetoken_create")
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, DetermineDBError(err, "emailchangetoken_create")
+	}
+	return changeToken, nil
+}
+
+// Confirm spends a plaintext email change token and moves its user to the
+// new address. Expired and unknown tokens both come back as
+// ErrRecordNotFound, and an address taken since the change was requested as
+// ErrDuplicateKey.
+func (m EmailChangeTokenModel) Confirm(ctx context.Context, token string) (*EmailChange, error) {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, DetermineDBError(err, "emailchangetoken_confirm")
+	}
+	defer tx.Rollback()
+
+	const consumeQuery = `
+	DELETE FROM email_change_tokens
+	WHERE token_hash = $1 AND expiration > now()
+	RETURNING user_id, new_email
+	`
+	change := &EmailChange{}
+	err = tx.QueryRowContext(ctx, consumeQuery, utils.HashToken(token)).Scan(&change.UserID, &change.NewEmail)
+	if err != nil {
+		return nil, DetermineDBError(err, "emailchangetoken_confirm")
+	}
+
+	const updateQuery = `
+	UPDATE users u
+	SET email = $2, updated_at = now(), version = u.version + 1
+	FROM (SELECT id, email FROM users WHERE id = $1 FOR UPDATE) old
+	WHERE u.id = old.id
+	RETURNING old.email, u.updated_at
+	`
+	err = tx.QueryRowContext(ctx, updateQuery, change.UserID, change.NewEmail).Scan(&change.OldEmail, &change.Timestamp)
+	if err != nil {
+		return nil, DetermineDBError(err, "emailchangetoken_confirm")
+	}
+
+	const cleanupQuery = `
+	DELETE FROM email_change_tokens
+	WHERE user_id = $1
+	`
+	if _, err = tx.ExecContext(ctx, cleanupQuery, change.UserID); err != nil {
+		return nil, DetermineDBError(err, "emailchangetoken_confirm")
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, DetermineDBError(err, "emailchangetoken_confirm")
+	}
+	return change, nil
+}
@@ -0,0 +1,168 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"strings"
+	"time"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// FeedCursor marks the last article of a feed page. The next page starts
+// strictly after it in (published_at, id) order, so articles published in
+// the meantime never shift or repeat what the reader has already seen.
+type FeedCursor struct {
+	PublishedAt time.Time
+	ID          string
+}
+
+func (c FeedCursor) Encode() string {
+	raw := c.PublishedAt.UTC().Format(time.RFC3339Nano) + "|" + c.ID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func DecodeFeedCursor(s string) (*FeedCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	publishedAt, id, ok := strings.Cut(string(raw), "|")
+	if !ok || id == "" {
+		return nil, ErrInvalidCursor
+	}
+	cursor := &FeedCursor{ID: id}
+	cursor.PublishedAt, err = time.Parse(time.RFC3339Nano, publishedAt)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return cursor, nil
+}
+
+// FeedItem is a published article in a reader's feed, with the reasons it
+// is there: "author", "tag" or "category", for whichever of the article's
+// author, tags and category the reader follows.
+type FeedItem struct {
+	Article
+	Author  ArticleAuthor `json:"author"`
+	Reasons []string      `json:"reasons"`
+}
+
+type FeedModel struct {
+	DB *sql.DB
+}
+
+// Get returns up to limit published articles for userID's feed, newest
+// first, starting after cursor when it isn't nil. An article that reaches
+// the reader several ways appears once, with every reason.
+func (m *FeedModel) Get(ctx context.Context, userID string, cursor *FeedCursor, limit int) ([]*FeedItem, error) {
+	// each source is cut to its own page before they are merged, so the
+	// query never reads further into any of them than one page needs
+	const page = `
+		AND a.status = 'published'
+		AND a.published_at <= now()
+		AND a.author_id <> $1
+		AND ($2::timestamptz IS NULL OR (a.published_at, a.id) < ($2::timestamptz, $3::uuid))
+		ORDER BY a.published_at DESC, a.id DESC
+		LIMIT $4`
+	query := fmt.Sprintf(`
+	WITH sources AS (
+		(SELECT a.id, a.published_at, 'author' AS reason
+		FROM articles a
+		JOIN followers f ON f.followed_id = a.author_id
+		WHERE f.follower_id = $1 %[1]s)
+		UNION ALL
+		(SELECT a.id, a.published_at, 'tag'
+		FROM articles a
+		WHERE EXISTS (
+			SELECT 1
+			FROM article_tags atg
+			JOIN tag_follows tf ON tf.tag_id = atg.tag_id
+			WHERE atg.article_id = a.id AND tf.user_id = $1
+		) %[1]s)
+		UNION ALL
+		(SELECT a.id, a.published_at, 'category'
+		FROM articles a
+		JOIN category_follows cf ON cf.category_id = a.category_id
+		WHERE cf.user_id = $1 %[1]s)
+	), feed AS (
+		SELECT id, published_at, array_agg(DISTINCT reason ORDER BY reason) AS reasons
+		FROM sources
+		GROUP BY id, published_at
+		ORDER BY published_at DESC, id DESC
+		LIMIT $4
+	)
+	SELECT a.id, a.author_id, a.title, a.slug, a.content, a.status, a.version,
+		COALESCE(a.category_id, 0), COALESCE(c.name, ''),
+		COALESCE((
+			SELECT array_agg(t.name ORDER BY t.name)
+			FROM article_tags atg
+			JOIN tags t ON t.id = atg.tag_id
+			WHERE atg.article_id = a.id
+		), '{}'),
+		a.created_at, a.updated_at, a.published_at,
+		u.id, u.username, u.first_name, u.last_name, u.bio, u.profile_picture_url,
+		feed.reasons
+	FROM feed
+	JOIN articles a ON a.id = feed.id
+	JOIN users u ON u.id = a.author_id
+	LEFT JOIN categories c ON c.id = a.category_id
+	ORDER BY feed.published_at DESC, feed.id DESC
+	`, page)
+	var after *time.Time
+	var afterID *string
+	if cursor != nil {
+		after, afterID = &cursor.PublishedAt, &cursor.ID
+	}
+	rows, err := m.DB.QueryContext(
+		ctx,
+		query,
+		userID,
+		after,
+		afterID,
+		limit,
+	)
+	if err != nil {
+		return nil, DetermineDBError(err, "feed_get")
+	}
+	defer rows.Close()
+
+	items := []*FeedItem{}
+	for rows.Next() {
+		item := &FeedItem{}
+		err = rows.Scan(
+			&item.ID,
+			&item.AuthorID,
+			&item.Title,
+			&item.Slug,
+			&item.Content,
+			&item.Status,
+			&item.Version,
+			&item.CategoryID,
+			&item.Category,
+			pq.Array(&item.Tags),
+			&item.CreatedAt,
+			&item.UpdatedAt,
+			&item.PublishedAt,
+			&item.Author.ID,
+			&item.Author.Username,
+			&item.Author.FirstName,
+			&item.Author.LastName,
+			&item.Author.Bio,
+			&item.Author.ProfilePicUrl,
+			pq.Array(&item.Reasons),
+		)
+		if err != nil {
+			return nil, DetermineDBError(err, "feed_get")
+		}
+		items = append(items, item)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, DetermineDBError(err, "feed_get")
+	}
+	return items, nil
+}
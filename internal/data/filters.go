@@ -6,14 +6,15 @@ import (
 )
 
 type Filters struct {
-	Page         int
-	PageSize     int
-	Sort         string
+	Page         int    `validate:"min=1,max=10000000"`
+	PageSize     int    `validate:"min=1,max=100"`
+	Sort         string `validate:"required"`
 	SortSafeList []string
-	Search       string
+	Search       string `validate:"max=255"`
 	Category     string
 	Tags         []string
-	Status       string
+	Status       string `validate:"omitempty,oneof=draft scheduled published"`
+	AuthorID     string
 }
 
 type Metadata struct {
@@ -37,6 +38,18 @@ func calculateMetadata(totalRecords, page, pageSize int) Metadata {
 	}
 }
 
+// SortIsSafe reports whether the requested sort value is one of the values
+// the caller has allowed. It must be checked before the filters reach a query,
+// since column() panics on anything outside the safe list.
+func (f Filters) SortIsSafe() bool {
+	for _, safeValue := range f.SortSafeList {
+		if f.Sort == safeValue {
+			return true
+		}
+	}
+	return false
+}
+
 func (f Filters) column() string {
 	for _, safeValue := range f.SortSafeList {
 		if f.Sort == safeValue {
@@ -47,6 +60,9 @@ func (f Filters) column() string {
 }
 
 func (f Filters) direction() string {
+	if strings.HasPrefix(f.Sort, "-") {
+		return "DESC"
+	}
 	return "ASC"
 }
 
@@ -58,67 +58,99 @@ func (m *FollowerModel) UnfollowUser(ctx context.Context, followerID, followedID
 	return nil
 }
 
-func (m *FollowerModel) ListFollowers(ctx context.Context, userID string) ([]*Follower, error) {
+// FollowListEntry is a user in someone's follower or following list, with
+// how they relate to whoever is looking at the list.
+type FollowListEntry struct {
+	UserSummary
+	Bio              string    `json:"bio"`
+	FollowedAt       time.Time `json:"followed_at"`
+	FollowedByViewer bool      `json:"followed_by_viewer"`
+	FollowsViewer    bool      `json:"follows_viewer"`
+}
+
+// ListFollowers returns the users following userID, most recent first.
+func (m *FollowerModel) ListFollowers(ctx context.Context, userID, viewerID string, filters Filters) ([]*FollowListEntry, Metadata, error) {
 	const query = `
-	SELECT (follower_id, followed_id, created_at) FROM followers
-	WHERE followed_id = $1
+	SELECT count(*) OVER(), u.id, u.username, u.first_name, u.last_name, u.profile_picture_url, u.bio,
+		f.created_at,
+		EXISTS (SELECT 1 FROM followers WHERE follower_id = $2::uuid AND followed_id = u.id),
+		EXISTS (SELECT 1 FROM followers WHERE follower_id = u.id AND followed_id = $2::uuid)
+	FROM followers f
+	JOIN users u ON u.id = f.follower_id
+	WHERE f.followed_id = $1
+	ORDER BY f.created_at DESC, u.id
+	LIMIT $3 OFFSET $4
 	`
-	rows, err := m.DB.QueryContext(
-		ctx,
-		query,
-		userID)
-	if err != nil {
-		return nil, DetermineDBError(err, "followers_listfollowers")
-	}
-	defer rows.Close()
-	var followers []*Follower
-	for rows.Next() {
-		var follower *Follower
-		err = rows.Scan(
-			&follower.FollowerID,
-			&follower.FollowedID,
-			&follower.CreatedAt,
-		)
-		if err != nil {
-			return nil, DetermineDBError(err, "followers_listfollowers")
-		}
-		followers = append(followers, follower)
-	}
-	if err = rows.Err(); err != nil {
-		return nil, DetermineDBError(err, "followers_listfollowers")
-	}
-	return followers, nil
+	return m.listFollowEntries(ctx, query, "followers_listfollowers", userID, viewerID, filters)
 }
 
-func (m *FollowerModel) ListUsersFollowed(ctx context.Context, userID string) ([]*Follower, error) {
+// ListUsersFollowed returns the users userID follows, most recent first.
+func (m *FollowerModel) ListUsersFollowed(ctx context.Context, userID, viewerID string, filters Filters) ([]*FollowListEntry, Metadata, error) {
 	const query = `
-	SELECT (follower_id, followed_id, created_at) FROM followers
-	WHERE follower_id = $1
+	SELECT count(*) OVER(), u.id, u.username, u.first_name, u.last_name, u.profile_picture_url, u.bio,
+		f.created_at,
+		EXISTS (SELECT 1 FROM followers WHERE follower_id = $2::uuid AND followed_id = u.id),
+		EXISTS (SELECT 1 FROM followers WHERE follower_id = u.id AND followed_id = $2::uuid)
+	FROM followers f
+	JOIN users u ON u.id = f.followed_id
+	WHERE f.follower_id = $1
+	ORDER BY f.created_at DESC, u.id
+	LIMIT $3 OFFSET $4
 	`
+	return m.listFollowEntries(ctx, query, "followers_listusersfollowed", userID, viewerID, filters)
+}
+
+func (m *FollowerModel) listFollowEntries(ctx context.Context, query, operation, userID, viewerID string, filters Filters) ([]*FollowListEntry, Metadata, error) {
 	rows, err := m.DB.QueryContext(
 		ctx,
 		query,
 		userID,
+		viewerID,
+		filters.limit(),
+		filters.offset(),
 	)
 	if err != nil {
-		return nil, DetermineDBError(err, "followers_listfollowers")
+		return nil, Metadata{}, DetermineDBError(err, operation)
 	}
 	defer rows.Close()
-	var usersFollowed []*Follower
+
+	totalRecords := 0
+	entries := []*FollowListEntry{}
 	for rows.Next() {
-		var userFollowed *Follower
+		entry := &FollowListEntry{}
 		err = rows.Scan(
-			&userFollowed.FollowerID,
-			&userFollowed.FollowedID,
-			&userFollowed.CreatedAt,
+			&totalRecords,
+			&entry.ID,
+			&entry.Username,
+			&entry.FirstName,
+			&entry.LastName,
+			&entry.ProfilePicUrl,
+			&entry.Bio,
+			&entry.FollowedAt,
+			&entry.FollowedByViewer,
+			&entry.FollowsViewer,
 		)
 		if err != nil {
-			return nil, DetermineDBError(err, "followers_listfollowers")
+			return nil, Metadata{}, DetermineDBError(err, operation)
 		}
-		usersFollowed = append(usersFollowed, userFollowed)
+		entries = append(entries, entry)
 	}
 	if err = rows.Err(); err != nil {
-		return nil, DetermineDBError(err, "followers_listfollowers")
+		return nil, Metadata{}, DetermineDBError(err, operation)
+	}
+	return entries, calculateMetadata(totalRecords, filters.Page, filters.PageSize), nil
+}
+
+// Counts returns how many users follow userID and how many userID follows.
+func (m *FollowerModel) Counts(ctx context.Context, userID string) (followers int, following int, err error) {
+	const query = `
+	SELECT
+		(SELECT count(*) FROM followers WHERE followed_id = $1),
+		(SELECT count(*) FROM followers WHERE follower_id = $1)
+	`
+	err = m.DB.QueryRowContext(ctx, query, userID).Scan(&followers, &following)
+	if err != nil {
+		return 0, 0, DetermineDBError(err, "followers_counts")
 	}
-	return usersFollowed, nil
+	return followers, following, nil
 }
@@ -0,0 +1,204 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"github.com/lib/pq"
+	"time"
+)
+
+// MFASettings is a user's two-factor state. Secret is set as soon as
+// enrollment starts, but only counts once Enabled is true.
+type MFASettings struct {
+	UserID   string
+	Secret   *string
+	Enabled  bool
+	LastStep int64
+}
+
+type MFAModel struct {
+	DB *sql.DB
+}
+
+func (m MFAModel) Get(ctx context.Context, userID string) (*MFASettings, error) {
+	const query = `
+	SELECT id, totp_secret, totp_enabled, totp_last_step
+	FROM users
+	WHERE id = $1
+	`
+	settings := &MFASettings{}
+	err := m.DB.QueryRowContext(
+		ctx,
+		query,
+		userID,
+	).Scan(
+		&settings.UserID,
+		&settings.Secret,
+		&settings.Enabled,
+		&settings.LastStep,
+	)
+	if err != nil {
+		return nil, DetermineDBError(err, "mfa_get")
+	}
+	return settings, nil
+}
+
+// SetPendingSecret starts (or restarts) enrollment. It refuses to replace
+// the secret of a user who already has two-factor turned on.
+func (m MFAModel) SetPendingSecret(ctx context.Context, userID, secret string) (*ModifiedData, error) {
+	const query = `
+	UPDATE users
+	SET totp_secret = $1, totp_last_step = 0
+	WHERE id = $2 AND totp_enabled = false
+	RETURNING id
+	`
+	data := &ModifiedData{Timestamp: time.Now().UTC()}
+	err := m.DB.QueryRowContext(ctx, query, secret, userID).Scan(&data.ID)
+	if err != nil {
+		return nil, DetermineDBError(err, "mfa_setpendingsecret")
+	}
+	return data, nil
+}
+
+// Enable turns two-factor on once the first code has been confirmed, and
+// replaces any recovery codes with codeHashes.
+func (m MFAModel) Enable(ctx context.Context, userID string, step int64, codeHashes []string) (*ModifiedData, error) {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, DetermineDBError(err, "mfa_enable")
+	}
+	defer tx.Rollback()
+
+	const enableQuery = `
+	UPDATE users
+	SET totp_enabled = true, totp_last_step = $1, updated_at = now()
+	WHERE id = $2 AND totp_secret IS NOT NULL AND totp_enabled = false
+	RETURNING id, updated_at
+	`
+	data := &ModifiedData{}
+	err = tx.QueryRowContext(ctx, enableQuery, step, userID).Scan(&data.ID, &data.Timestamp)
+	if err != nil {
+		return nil, DetermineDBError(err, "mfa_enable")
+	}
+	if err = replaceRecoveryCodes(ctx, tx, userID, codeHashes); err != nil {
+		return nil, DetermineDBError(err, "mfa_enable")
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, DetermineDBError(err, "mfa_enable")
+	}
+	return data, nil
+}
+
+func (m MFAModel) Disable(ctx context.Context, userID string) (*ModifiedData, error) {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, DetermineDBError(err, "mfa_disable")
+	}
+	defer tx.Rollback()
+
+	const query = `
+	UPDATE users
+	SET totp_enabled = false, totp_secret = NULL, totp_last_step = 0, updated_at = now()
+	WHERE id = $1
+	RETURNING id, updated_at
+	`
+	data := &ModifiedData{}
+	err = tx.QueryRowContext(ctx, query, userID).Scan(&data.ID, &data.Timestamp)
+	if err != nil {
+		return nil, DetermineDBError(err, "mfa_disable")
+	}
+	if err = replaceRecoveryCodes(ctx, tx, userID, nil); err != nil {
+		return nil, DetermineDBError(err, "mfa_disable")
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, DetermineDBError(err, "mfa_disable")
+	}
+	return data, nil
+}
+
+// UseStep records step as the last accepted TOTP step. It reports false if
+// that step or a later one was already used, which means the code is being
+// replayed.
+func (m MFAModel) UseStep(ctx context.Context, userID string, step int64) (bool, error) {
+	const query = `
+	UPDATE users
+	SET totp_last_step = $1
+	WHERE id = $2 AND totp_last_step < $1
+	`
+	result, err := m.DB.ExecContext(ctx, query, step, userID)
+	if err != nil {
+		return false, DetermineDBError(err, "mfa_usestep")
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, DetermineDBError(err, "mfa_usestep")
+	}
+	return rows == 1, nil
+}
+
+// UseRecoveryCode spends one of the user's unused recovery codes. It
+// reports false if no unused code has that hash.
+func (m MFAModel) UseRecoveryCode(ctx context.Context, userID, codeHash string) (bool, error) {
+	const query = `
+	UPDATE mfa_recovery_codes
+	SET used_at = now()
+	WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`
+	result, err := m.DB.ExecContext(ctx, query, userID, codeHash)
+	if err != nil {
+		return false, DetermineDBError(err, "mfa_userecoverycode")
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, DetermineDBError(err, "mfa_userecoverycode")
+	}
+	return rows == 1, nil
+}
+
+func (m MFAModel) RegenerateRecoveryCodes(ctx context.Context, userID string, codeHashes []string) error {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return DetermineDBError(err, "mfa_regeneraterecoverycodes")
+	}
+	defer tx.Rollback()
+	if err = replaceRecoveryCodes(ctx, tx, userID, codeHashes); err != nil {
+		return DetermineDBError(err, "mfa_regeneraterecoverycodes")
+	}
+	if err = tx.Commit(); err != nil {
+		return DetermineDBError(err, "mfa_regeneraterecoverycodes")
+	}
+	return nil
+}
+
+func (m MFAModel) RemainingRecoveryCodes(ctx context.Context, userID string) (int, error) {
+	const query = `
+	SELECT count(*)
+	FROM mfa_recovery_codes
+	WHERE user_id = $1 AND used_at IS NULL
+	`
+	var count int
+	err := m.DB.QueryRowContext(ctx, query, userID).Scan(&count)
+	if err != nil {
+		return 0, DetermineDBError(err, "mfa_remainingrecoverycodes")
+	}
+	return count, nil
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx *sql.Tx, userID string, codeHashes []string) error {
+	const deleteQuery = `
+	DELETE FROM mfa_recovery_codes
+	WHERE user_id = $1
+	`
+	if _, err := tx.ExecContext(ctx, deleteQuery, userID); err != nil {
+		return err
+	}
+	if len(codeHashes) == 0 {
+		return nil
+	}
+	const insertQuery = `
+	INSERT INTO mfa_recovery_codes (user_id, code_hash)
+	SELECT $1, unnest($2::text[])
+	`
+	_, err := tx.ExecContext(ctx, insertQuery, userID, pq.Array(codeHashes))
+	return err
+}
@@ -19,13 +19,24 @@ var (
 )
 
 type Models struct {
-	Users       UserModel
-	ResetTokens ResetTokenModel
-	Articles    ArticleModel
-	Tags        TagModel
-	Comments    CommentModel
-	Followers   FollowerModel
-	Categories  CategoryModel
+	Users            UserModel
+	ResetTokens      ResetTokenModel
+	ActivationTokens ActivationTokenModel
+	EmailChanges     EmailChangeTokenModel
+	Sessions         SessionModel
+	MFA              MFAModel
+	Articles         ArticleModel
+	Revisions        ArticleRevisionModel
+	Tags             TagModel
+	TagFollows       TagFollowModel
+	Comments         CommentModel
+	Followers        FollowerModel
+	Feed             FeedModel
+	Trending         TrendingModel
+	Statistics       ArticleStatisticsModel
+	Categories       CategoryModel
+	CategoryFollows  CategoryFollowModel
+	Notifications    NotificationModel
 }
 
 type DBError struct {
@@ -107,11 +118,23 @@ func categorizePostgresError(pqErr *pq.Error, operation string) error {
 
 func NewModels(db *sql.DB) Models {
 	return Models{
-		Users:       UserModel{DB: db},
-		ResetTokens: ResetTokenModel{DB: db},
-		Followers:   FollowerModel{DB: db},
-		Tags:        TagModel{DB: db},
-		Categories:  CategoryModel{DB: db},
-		Articles:    ArticleModel{DB: db},
+		Users:            UserModel{DB: db},
+		ResetTokens:      ResetTokenModel{DB: db},
+		ActivationTokens: ActivationTokenModel{DB: db},
+		EmailChanges:     EmailChangeTokenModel{DB: db},
+		Sessions:         SessionModel{DB: db},
+		MFA:              MFAModel{DB: db},
+		Followers:        FollowerModel{DB: db},
+		Feed:             FeedModel{DB: db},
+		Trending:         TrendingModel{DB: db},
+		Statistics:       ArticleStatisticsModel{DB: db},
+		Tags:             TagModel{DB: db},
+		TagFollows:       TagFollowModel{DB: db},
+		Categories:       CategoryModel{DB: db},
+		CategoryFollows:  CategoryFollowModel{DB: db},
+		Articles:         ArticleModel{DB: db},
+		Comments:         CommentModel{DB: db},
+		Notifications:    NotificationModel{DB: db},
+		Revisions:        ArticleRevisionModel{DB: db},
 	}
 }
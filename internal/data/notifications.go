@@ -0,0 +1,245 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+const (
+	NotificationFollow  = "follow"
+	NotificationLike    = "like"
+	NotificationComment = "comment"
+	NotificationReply   = "reply"
+)
+
+type Notification struct {
+	ID          string    `json:"id"`
+	RecipientID string    `json:"recipient_id"`
+	ActorID     string    `json:"actor_id"`
+	Type        string    `json:"type"`
+	ArticleID   *string   `json:"article_id"`
+	CommentID   *string   `json:"comment_id"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// NotificationGroup folds notifications of the same kind about the same
+// thing into one entry, e.g. every like on an article. ID is the latest
+// notification in the group and can be used to mark the whole group read.
+type NotificationGroup struct {
+	ID           string      `json:"id"`
+	Type         string      `json:"type"`
+	ArticleID    *string     `json:"article_id"`
+	ArticleTitle string      `json:"article_title,omitempty"`
+	CommentID    *string     `json:"comment_id"`
+	LatestActor  UserSummary `json:"latest_actor"`
+	ActorCount   int         `json:"actor_count"`
+	Message      string      `json:"message"`
+	Read         bool        `json:"read"`
+	LatestAt     time.Time   `json:"latest_at"`
+}
+
+type NotificationModel struct {
+	DB *sql.DB
+}
+
+// groupKey decides which notifications are folded together: likes and
+// comments per article, replies per comment replied to, follows per recipient.
+func (n *Notification) groupKey() string {
+	switch n.Type {
+	case NotificationLike, NotificationComment:
+		if n.ArticleID != nil {
+			return n.Type + ":" + *n.ArticleID
+		}
+	case NotificationReply:
+		if n.CommentID != nil {
+			return n.Type + ":" + *n.CommentID
+		}
+	}
+	return n.Type
+}
+
+func (m *NotificationModel) Create(ctx context.Context, notification *Notification) error {
+	const query = `
+	INSERT INTO notifications (recipient_id, actor_id, type, article_id, comment_id, group_key)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	ON CONFLICT (recipient_id, group_key, actor_id) WHERE type IN ('follow', 'like') DO NOTHING
+	`
+	_, err := m.DB.ExecContext(
+		ctx,
+		query,
+		notification.RecipientID,
+		notification.ActorID,
+		notification.Type,
+		notification.ArticleID,
+		notification.CommentID,
+		notification.groupKey(),
+	)
+	if err != nil {
+		return DetermineDBError(err, "notification_create")
+	}
+	return nil
+}
+
+func (m *NotificationModel) ListGroups(ctx context.Context, userID string, unreadOnly bool, filters Filters) ([]*NotificationGroup, Metadata, error) {
+	const query = `
+	WITH groups AS (
+		SELECT group_key,
+			read_at IS NOT NULL AS read,
+			max(created_at) AS latest_at,
+			count(DISTINCT actor_id) AS actor_count,
+			(array_agg(id ORDER BY created_at DESC))[1] AS latest_id
+		FROM notifications
+		WHERE recipient_id = $1 AND (read_at IS NULL OR NOT $2)
+		GROUP BY group_key, read_at IS NOT NULL
+	)
+	SELECT count(*) OVER(), n.id, n.type, n.article_id, COALESCE(a.title, ''), n.comment_id,
+		g.read, g.actor_count, g.latest_at,
+		u.id, u.username, u.first_name, u.last_name, u.profile_picture_url
+	FROM groups g
+	JOIN notifications n ON n.id = g.latest_id
+	JOIN users u ON u.id = n.actor_id
+	LEFT JOIN articles a ON a.id = n.article_id
+	ORDER BY g.latest_at DESC, n.id
+	LIMIT $3 OFFSET $4
+	`
+	rows, err := m.DB.QueryContext(ctx, query, userID, unreadOnly, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, DetermineDBError(err, "notification_listgroups")
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	groups := []*NotificationGroup{}
+	for rows.Next() {
+		group := &NotificationGroup{}
+		err = rows.Scan(
+			&totalRecords,
+			&group.ID,
+			&group.Type,
+			&group.ArticleID,
+			&group.ArticleTitle,
+			&group.CommentID,
+			&group.Read,
+			&group.ActorCount,
+			&group.LatestAt,
+			&group.LatestActor.ID,
+			&group.LatestActor.Username,
+			&group.LatestActor.FirstName,
+			&group.LatestActor.LastName,
+			&group.LatestActor.ProfilePicUrl,
+		)
+		if err != nil {
+			return nil, Metadata{}, DetermineDBError(err, "notification_listgroups")
+		}
+		group.Message = group.message()
+		groups = append(groups, group)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, DetermineDBError(err, "notification_listgroups")
+	}
+	return groups, calculateMetadata(totalRecords, filters.Page, filters.PageSize), nil
+}
+
+func (g *NotificationGroup) message() string {
+	var action string
+	switch g.Type {
+	case NotificationFollow:
+		action = "followed you"
+	case NotificationLike:
+		action = "liked your article"
+	case NotificationComment:
+		action = "commented on your article"
+	case NotificationReply:
+		action = "replied to your comment"
+	}
+	if g.ArticleTitle != "" && g.Type != NotificationFollow {
+		action = fmt.Sprintf("%s \"%s\"", action, g.ArticleTitle)
+	}
+
+	name := g.LatestActor.FirstName + " " + g.LatestActor.LastName
+	switch {
+	case g.ActorCount <= 1:
+		return fmt.Sprintf("%s %s", name, action)
+	case g.ActorCount == 2:
+		return fmt.Sprintf("%s and 1 other person %s", name, action)
+	case g.ActorCount < 5:
+		return fmt.Sprintf("%s and %d others %s", name, g.ActorCount-1, action)
+	default:
+		return fmt.Sprintf("%d people %s", g.ActorCount, action)
+	}
+}
+
+// UnreadCount returns the number of unread notification groups, which is what
+// a badge next to the list should show.
+func (m *NotificationModel) UnreadCount(ctx context.Context, userID string) (int, error) {
+	const query = `
+	SELECT count(DISTINCT group_key)
+	FROM notifications
+	WHERE recipient_id = $1 AND read_at IS NULL
+	`
+	var count int
+	err := m.DB.QueryRowContext(ctx, query, userID).Scan(&count)
+	if err != nil {
+		return 0, DetermineDBError(err, "notification_unreadcount")
+	}
+	return count, nil
+}
+
+// MarkGroupRead marks every notification in the same group as id as read.
+func (m *NotificationModel) MarkGroupRead(ctx context.Context, userID, id string) (*ModifiedData, error) {
+	const query = `
+	WITH target AS (
+		SELECT group_key FROM notifications
+		WHERE id = $1 AND recipient_id = $2
+	), updated AS (
+		UPDATE notifications
+		SET read_at = now()
+		WHERE recipient_id = $2
+		AND read_at IS NULL
+		AND group_key = (SELECT group_key FROM target)
+	)
+	SELECT group_key FROM target
+	`
+	var groupKey string
+	err := m.DB.QueryRowContext(ctx, query, id, userID).Scan(&groupKey)
+	if err != nil {
+		return nil, DetermineDBError(err, "notification_markgroupread")
+	}
+	return &ModifiedData{ID: id, Timestamp: time.Now().UTC()}, nil
+}
+
+func (m *NotificationModel) MarkAllRead(ctx context.Context, userID string) (*ModifiedData, error) {
+	const query = `
+	UPDATE notifications
+	SET read_at = now()
+	WHERE recipient_id = $1 AND read_at IS NULL
+	`
+	_, err := m.DB.ExecContext(ctx, query, userID)
+	if err != nil {
+		return nil, DetermineDBError(err, "notification_markallread")
+	}
+	return &ModifiedData{ID: userID, Timestamp: time.Now().UTC()}, nil
+}
+
+// DeleteGroup removes every notification in the same group as id.
+func (m *NotificationModel) DeleteGroup(ctx context.Context, userID, id string) (*ModifiedData, error) {
+	const query = `
+	DELETE FROM notifications
+	WHERE recipient_id = $2
+	AND group_key = (
+		SELECT group_key FROM notifications
+		WHERE id = $1 AND recipient_id = $2
+	)
+	RETURNING id
+	`
+	data := &ModifiedData{}
+	err := m.DB.QueryRowContext(ctx, query, id, userID).Scan(&data.ID)
+	if err != nil {
+		return nil, DetermineDBError(err, "notification_deletegroup")
+	}
+	data.ID = id
+	data.Timestamp = time.Now().UTC()
+	return data, nil
+}
@@ -16,6 +16,13 @@ func NilIfEmpty(s string) *string {
 	return &s
 }
 
+func NilIfZero(i int) *int {
+	if i == 0 {
+		return nil
+	}
+	return &i
+}
+
 func (ns NullString) MarshalJSON() ([]byte, error) {
 	if !ns.Valid {
 		return []byte("nil"), nil
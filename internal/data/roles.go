@@ -0,0 +1,121 @@
+package data
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	RoleReader = "reader"
+	RoleAuthor = "author"
+	RoleEditor = "editor"
+	RoleAdmin  = "admin"
+)
+
+// RoleChange is one entry in the audit trail of role assignments.
+type RoleChange struct {
+	ID        int       `json:"id"`
+	UserID    string    `json:"user_id"`
+	ChangedBy *string   `json:"changed_by"`
+	OldRole   string    `json:"old_role"`
+	NewRole   string    `json:"new_role"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// UpdateRole assigns a new role and records who did it in role_changes, in
+// one transaction.
+func (m *UserModel) UpdateRole(ctx context.Context, change *RoleChange) (*RoleChange, error) {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, DetermineDBError(err, "user_updaterole")
+	}
+	defer tx.Rollback()
+
+	const lockQuery = `
+	SELECT role
+	FROM users
+	WHERE id = $1
+	FOR UPDATE
+	`
+	newChange := &RoleChange{}
+	err = tx.QueryRowContext(ctx, lockQuery, change.UserID).Scan(&newChange.OldRole)
+	if err != nil {
+		return nil, DetermineDBError(err, "user_updaterole")
+	}
+
+	const updateQuery = `
+	UPDATE users
+	SET role = $1, updated_at = now(), version = version + 1
+	WHERE id = $2
+	`
+	if _, err = tx.ExecContext(ctx, updateQuery, change.NewRole, change.UserID); err != nil {
+		return nil, DetermineDBError(err, "user_updaterole")
+	}
+
+	const auditQuery = `
+	INSERT INTO role_changes (user_id, changed_by, old_role, new_role, reason)
+	VALUES ($1, $2, $3, $4, $5)
+	RETURNING id, user_id, changed_by, new_role, reason, created_at
+	`
+	err = tx.QueryRowContext(
+		ctx,
+		auditQuery,
+		change.UserID,
+		change.ChangedBy,
+		newChange.OldRole,
+		change.NewRole,
+		change.Reason,
+	).Scan(
+		&newChange.ID,
+		&newChange.UserID,
+		&newChange.ChangedBy,
+		&newChange.NewRole,
+		&newChange.Reason,
+		&newChange.CreatedAt,
+	)
+	if err != nil {
+		return nil, DetermineDBError(err, "user_updaterole")
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, DetermineDBError(err, "user_updaterole")
+	}
+	return newChange, nil
+}
+
+// GetRoleChanges returns a user's role history, newest first.
+func (m *UserModel) GetRoleChanges(ctx context.Context, userID string) ([]*RoleChange, error) {
+	const query = `
+	SELECT id, user_id, changed_by, old_role, new_role, reason, created_at
+	FROM role_changes
+	WHERE user_id = $1
+	ORDER BY created_at DESC, id DESC
+	`
+	rows, err := m.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, DetermineDBError(err, "user_getrolechanges")
+	}
+	defer rows.Close()
+
+	changes := []*RoleChange{}
+	for rows.Next() {
+		change := &RoleChange{}
+		err = rows.Scan(
+			&change.ID,
+			&change.UserID,
+			&change.ChangedBy,
+			&change.OldRole,
+			&change.NewRole,
+			&change.Reason,
+			&change.CreatedAt,
+		)
+		if err != nil {
+			return nil, DetermineDBError(err, "user_getrolechanges")
+		}
+		changes = append(changes, change)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, DetermineDBError(err, "user_getrolechanges")
+	}
+	return changes, nil
+}
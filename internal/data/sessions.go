@@ -0,0 +1,298 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/rx-rz/65ch/internal/utils"
+	"time"
+)
+
+// ErrRefreshTokenReused is returned when a refresh token that was already
+// rotated is presented again. By the time it is returned the whole session
+// has been revoked.
+var ErrRefreshTokenReused = errors.New("refresh token reuse detected")
+
+// Session is one signed in device. Its ID is the refresh token family, which
+// stays the same as the refresh token itself rotates.
+type Session struct {
+	ID         string    `json:"id"`
+	UserID     string    `json:"user_id"`
+	Device     string    `json:"device"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"`
+}
+
+type SessionModel struct {
+	DB *sql.DB
+}
+
+// Create starts a new session and returns it together with its first
+// plaintext refresh token. Only the token's hash is stored.
+func (m SessionModel) Create(ctx context.Context, session *Session, ttl time.Duration) (*Session, string, error) {
+	token, hash, err := utils.GenerateSecureToken()
+	if err != nil {
+		return nil, "", err
+	}
+	const query = `
+	INSERT INTO sessions (family_id, user_id, token_hash, user_agent, ip_address, expires_at)
+	VALUES (gen_random_uuid(), $1, $2, $3, $4, $5)
+	RETURNING family_id, user_id, user_agent, ip_address, signed_in_at, created_at, expires_at
+	`
+	newSession := &Session{}
+	err = m.DB.QueryRowContext(
+		ctx,
+		query,
+		session.UserID,
+		hash,
+		session.UserAgent,
+		session.IPAddress,
+		time.Now().Add(ttl).UTC(),
+	).Scan(
+		&newSession.ID,
+		&newSession.UserID,
+		&newSession.UserAgent,
+		&newSession.IPAddress,
+		&newSession.CreatedAt,
+		&newSession.LastUsedAt,
+		&newSession.ExpiresAt,
+	)
+	if err != nil {
+		return nil, "", DetermineDBError(err, "session_create")
+	}
+	return newSession, token, nil
+}
+
+// Rotate spends a refresh token and issues the next one in the same session.
+// Unknown, expired and revoked tokens are ErrRecordNotFound. A token that
+// was already rotated revokes the session and returns ErrRefreshTokenReused.
+func (m SessionModel) Rotate(ctx context.Context, token, userAgent, ipAddress string, ttl time.Duration) (*Session, string, error) {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, "", DetermineDBError(err, "session_rotate")
+	}
+	defer tx.Rollback()
+
+	const lookupQuery = `
+	SELECT id, family_id, user_id, signed_in_at, expires_at, rotated_at, revoked_at
+	FROM sessions
+	WHERE token_hash = $1
+	FOR UPDATE
+	`
+	var (
+		id                   string
+		session              = &Session{}
+		rotatedAt, revokedAt *time.Time
+	)
+	err = tx.QueryRowContext(
+		ctx,
+		lookupQuery,
+		utils.HashToken(token),
+	).Scan(
+		&id,
+		&session.ID,
+		&session.UserID,
+		&session.CreatedAt,
+		&session.ExpiresAt,
+		&rotatedAt,
+		&revokedAt,
+	)
+	if err != nil {
+		return nil, "", DetermineDBError(err, "session_rotate")
+	}
+	if revokedAt != nil || session.ExpiresAt.Before(time.Now()) {
+		return nil, "", &DBError{Err: ErrRecordNotFound, Operation: "session_rotate"}
+	}
+	if rotatedAt != nil {
+		if err = revokeFamily(ctx, tx, session.ID); err != nil {
+			return nil, "", DetermineDBError(err, "session_rotate")
+		}
+		if err = tx.Commit(); err != nil {
+			return nil, "", DetermineDBError(err, "session_rotate")
+		}
+		return nil, "", &DBError{Err: ErrRefreshTokenReused, Operation: "session_rotate"}
+	}
+
+	const rotateQuery = `
+	UPDATE sessions
+	SET rotated_at = now()
+	WHERE id = $1
+	`
+	if _, err = tx.ExecContext(ctx, rotateQuery, id); err != nil {
+		return nil, "", DetermineDBError(err, "session_rotate")
+	}
+
+	newToken, hash, err := utils.GenerateSecureToken()
+	if err != nil {
+		return nil, "", err
+	}
+	const insertQuery = `
+	INSERT INTO sessions (family_id, user_id, token_hash, user_agent, ip_address, signed_in_at, expires_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)
+	RETURNING user_agent, ip_address, created_at, expires_at
+	`
+	err = tx.QueryRowContext(
+		ctx,
+		insertQuery,
+		session.ID,
+		session.UserID,
+		hash,
+		userAgent,
+		ipAddress,
+		session.CreatedAt,
+		time.Now().Add(ttl).UTC(),
+	).Scan(
+		&session.UserAgent,
+		&session.IPAddress,
+		&session.LastUsedAt,
+		&session.ExpiresAt,
+	)
+	if err != nil {
+		return nil, "", DetermineDBError(err, "session_rotate")
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, "", DetermineDBError(err, "session_rotate")
+	}
+	return session, newToken, nil
+}
+
+// RevokeByToken ends the session a refresh token belongs to, whether or not
+// that token is still the current one.
+func (m SessionModel) RevokeByToken(ctx context.Context, token string) (*ModifiedData, error) {
+	const query = `
+	WITH revoked AS (
+		UPDATE sessions
+		SET revoked_at = now()
+		WHERE family_id = (SELECT family_id FROM sessions WHERE token_hash = $1)
+		AND revoked_at IS NULL
+		RETURNING family_id, revoked_at
+	)
+	SELECT family_id, max(revoked_at)
+	FROM revoked
+	GROUP BY family_id
+	`
+	data := &ModifiedData{}
+	err := m.DB.QueryRowContext(ctx, query, utils.HashToken(token)).Scan(&data.ID, &data.Timestamp)
+	if err != nil {
+		return nil, DetermineDBError(err, "session_revokebytoken")
+	}
+	return data, nil
+}
+
+// GetAllActiveForUser lists the user's signed in devices, most recently
+// used first.
+func (m SessionModel) GetAllActiveForUser(ctx context.Context, userID string) ([]*Session, error) {
+	const query = `
+	SELECT family_id, user_id, user_agent, ip_address, signed_in_at, created_at, expires_at
+	FROM sessions
+	WHERE user_id = $1
+	AND rotated_at IS NULL AND revoked_at IS NULL AND expires_at > now()
+	ORDER BY created_at DESC
+	`
+	rows, err := m.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, DetermineDBError(err, "session_getallactiveforuser")
+	}
+	defer rows.Close()
+
+	sessions := []*Session{}
+	for rows.Next() {
+		session := &Session{}
+		err = rows.Scan(
+			&session.ID,
+			&session.UserID,
+			&session.UserAgent,
+			&session.IPAddress,
+			&session.CreatedAt,
+			&session.LastUsedAt,
+			&session.ExpiresAt,
+		)
+		if err != nil {
+			return nil, DetermineDBError(err, "session_getallactiveforuser")
+		}
+		session.Device = utils.DescribeDevice(session.UserAgent)
+		sessions = append(sessions, session)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, DetermineDBError(err, "session_getallactiveforuser")
+	}
+	return sessions, nil
+}
+
+// IsActive reports whether a session can still be used, i.e. it has not
+// been revoked and its refresh token has not expired.
+func (m SessionModel) IsActive(ctx context.Context, id string) (bool, error) {
+	const query = `
+	SELECT EXISTS (
+		SELECT 1
+		FROM sessions
+		WHERE family_id = $1
+		AND rotated_at IS NULL AND revoked_at IS NULL AND expires_at > now()
+	)
+	`
+	var active bool
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(&active)
+	if err != nil {
+		return false, DetermineDBError(err, "session_isactive")
+	}
+	return active, nil
+}
+
+// Revoke ends one of the user's sessions.
+func (m SessionModel) Revoke(ctx context.Context, userID, id string) (*ModifiedData, error) {
+	const query = `
+	WITH revoked AS (
+		UPDATE sessions
+		SET revoked_at = now()
+		WHERE family_id = $1 AND user_id = $2 AND revoked_at IS NULL
+		RETURNING family_id, revoked_at
+	)
+	SELECT family_id, max(revoked_at)
+	FROM revoked
+	GROUP BY family_id
+	`
+	data := &ModifiedData{}
+	err := m.DB.QueryRowContext(ctx, query, id, userID).Scan(&data.ID, &data.Timestamp)
+	if err != nil {
+		return nil, DetermineDBError(err, "session_revoke")
+	}
+	return data, nil
+}
+
+// RevokeAllForUser ends every session the user has except keepID, which may
+// be empty to sign the user out everywhere. It returns how many sessions
+// were ended.
+func (m SessionModel) RevokeAllForUser(ctx context.Context, userID, keepID string) (int, error) {
+	const query = `
+	WITH revoked AS (
+		UPDATE sessions
+		SET revoked_at = now()
+		WHERE user_id = $1 AND revoked_at IS NULL
+		AND family_id::text <> $2
+		RETURNING family_id, rotated_at
+	)
+	SELECT count(*)
+	FROM revoked
+	WHERE rotated_at IS NULL
+	`
+	var count int
+	err := m.DB.QueryRowContext(ctx, query, userID, keepID).Scan(&count)
+	if err != nil {
+		return 0, DetermineDBError(err, "session_revokeallforuser")
+	}
+	return count, nil
+}
+
+func revokeFamily(ctx context.Context, tx *sql.Tx, familyID string) error {
+	const query = `
+	UPDATE sessions
+	SET revoked_at = now()
+	WHERE family_id = $1 AND revoked_at IS NULL
+	`
+	_, err := tx.ExecContext(ctx, query, familyID)
+	return err
+}
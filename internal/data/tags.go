@@ -11,6 +11,8 @@ type Tag struct {
 	Name      string    `json:"string"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"last_updated_at"`
+	// FollowerCount is only filled in by GetAll.
+	FollowerCount int `json:"follower_count"`
 }
 
 type TagModel struct {
@@ -42,9 +44,10 @@ func (m *TagModel) Create(ctx context.Context, name string) (*Tag, error) {
 
 func (m *TagModel) GetAll(ctx context.Context) ([]*Tag, error) {
 	const query = `
-	SELECT id, name 
-	FROM tags	
-	ORDER BY created_at DESC 
+	SELECT t.id, t.name,
+		(SELECT count(*) FROM tag_follows WHERE tag_id = t.id)
+	FROM tags t
+	ORDER BY t.created_at DESC
 	`
 
 	rows, err := m.DB.QueryContext(ctx, query)
@@ -58,7 +61,7 @@ func (m *TagModel) GetAll(ctx context.Context) ([]*Tag, error) {
 
 	for rows.Next() {
 		var t Tag
-		err := rows.Scan(&t.ID, &t.Name)
+		err := rows.Scan(&t.ID, &t.Name, &t.FollowerCount)
 		if err != nil {
 			return nil, DetermineDBError(err, "tag_getall")
 		}
@@ -120,6 +123,7 @@ func (m *TagModel) UpdateName(ctx context.Context, tag *Tag) (*ModifiedData, err
 	UPDATE tags SET name = $1, 
 	updated_at = $2 
 	WHERE id = $3
+	RETURNING id
 	`
 	data := &ModifiedData{}
 	updateTimestamp := time.Now().UTC()
@@ -142,6 +146,7 @@ func (m *TagModel) Delete(ctx context.Context, id int) (*ModifiedData, error) {
 	const query = `
 	   DELETE FROM tags 
        WHERE id = $1
+	   RETURNING id
 	`
 	data := &ModifiedData{
 		Timestamp: time.Now().UTC(),
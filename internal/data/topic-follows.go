@@ -0,0 +1,175 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"time"
+)
+
+// FollowedTopic is a tag or category a user follows.
+type FollowedTopic struct {
+	ID            int       `json:"id"`
+	Name          string    `json:"name"`
+	FollowerCount int       `json:"follower_count"`
+	FollowedAt    time.Time `json:"followed_at"`
+}
+
+type TagFollowModel struct {
+	DB *sql.DB
+}
+
+func (m *TagFollowModel) Follow(ctx context.Context, userID string, tagID int) (*ModifiedData, error) {
+	// following twice keeps the original follow
+	const query = `
+	INSERT INTO tag_follows (user_id, tag_id)
+	VALUES ($1, $2)
+	ON CONFLICT (user_id, tag_id) DO UPDATE SET followed_at = tag_follows.followed_at
+	RETURNING tag_id, followed_at
+	`
+	var id int
+	data := &ModifiedData{}
+	err := m.DB.QueryRowContext(
+		ctx,
+		query,
+		userID,
+		tagID,
+	).Scan(
+		&id,
+		&data.Timestamp,
+	)
+	if err != nil {
+		return nil, DetermineDBError(err, "tagfollow_follow")
+	}
+	data.ID = strconv.Itoa(id)
+	return data, nil
+}
+
+func (m *TagFollowModel) Unfollow(ctx context.Context, userID string, tagID int) (*ModifiedData, error) {
+	const query = `
+	DELETE FROM tag_follows
+	WHERE user_id = $1
+	AND tag_id = $2
+	RETURNING tag_id
+	`
+	var id int
+	err := m.DB.QueryRowContext(
+		ctx,
+		query,
+		userID,
+		tagID,
+	).Scan(&id)
+	if err != nil {
+		return nil, DetermineDBError(err, "tagfollow_unfollow")
+	}
+	return &ModifiedData{ID: strconv.Itoa(id), Timestamp: time.Now().UTC()}, nil
+}
+
+// GetAllForUser returns the tags userID follows, most recently followed
+// first.
+func (m *TagFollowModel) GetAllForUser(ctx context.Context, userID string) ([]*FollowedTopic, error) {
+	const query = `
+	SELECT t.id, t.name,
+		(SELECT count(*) FROM tag_follows WHERE tag_id = t.id),
+		tf.followed_at
+	FROM tag_follows tf
+	JOIN tags t ON t.id = tf.tag_id
+	WHERE tf.user_id = $1
+	ORDER BY tf.followed_at DESC
+	`
+	rows, err := m.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, DetermineDBError(err, "tagfollow_getallforuser")
+	}
+	return scanFollowedTopics(rows, "tagfollow_getallforuser")
+}
+
+type CategoryFollowModel struct {
+	DB *sql.DB
+}
+
+func (m *CategoryFollowModel) Follow(ctx context.Context, userID string, categoryID int) (*ModifiedData, error) {
+	const query = `
+	INSERT INTO category_follows (user_id, category_id)
+	VALUES ($1, $2)
+	ON CONFLICT (user_id, category_id) DO UPDATE SET followed_at = category_follows.followed_at
+	RETURNING category_id, followed_at
+	`
+	var id int
+	data := &ModifiedData{}
+	err := m.DB.QueryRowContext(
+		ctx,
+		query,
+		userID,
+		categoryID,
+	).Scan(
+		&id,
+		&data.Timestamp,
+	)
+	if err != nil {
+		return nil, DetermineDBError(err, "categoryfollow_follow")
+	}
+	data.ID = strconv.Itoa(id)
+	return data, nil
+}
+
+func (m *CategoryFollowModel) Unfollow(ctx context.Context, userID string, categoryID int) (*ModifiedData, error) {
+	const query = `
+	DELETE FROM category_follows
+	WHERE user_id = $1
+	AND category_id = $2
+	RETURNING category_id
+	`
+	var id int
+	err := m.DB.QueryRowContext(
+		ctx,
+		query,
+		userID,
+		categoryID,
+	).Scan(&id)
+	if err != nil {
+		return nil, DetermineDBError(err, "categoryfollow_unfollow")
+	}
+	return &ModifiedData{ID: strconv.Itoa(id), Timestamp: time.Now().UTC()}, nil
+}
+
+// GetAllForUser returns the categories userID follows, most recently
+// followed first.
+func (m *CategoryFollowModel) GetAllForUser(ctx context.Context, userID string) ([]*FollowedTopic, error) {
+	const query = `
+	SELECT c.id, c.name,
+		(SELECT count(*) FROM category_follows WHERE category_id = c.id),
+		cf.followed_at
+	FROM category_follows cf
+	JOIN categories c ON c.id = cf.category_id
+	WHERE cf.user_id = $1
+	ORDER BY cf.followed_at DESC
+	`
+	rows, err := m.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, DetermineDBError(err, "categoryfollow_getallforuser")
+	}
+	return scanFollowedTopics(rows, "categoryfollow_getallforuser")
+}
+
+func scanFollowedTopics(rows *sql.Rows, operation string) ([]*FollowedTopic, error) {
+	defer rows.Close()
+	topics := []*FollowedTopic{}
+	for rows.Next() {
+		topic := &FollowedTopic{}
+		err := rows.Scan(
+			&topic.ID,
+			&topic.Name,
+			&topic.FollowerCount,
+			&topic.FollowedAt,
+		)
+		if err != nil {
+			return nil, DetermineDBError(err, operation)
+		}
+		topics = append(topics, topic)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, DetermineDBError(err, operation)
+	}
+	return topics, nil
+}
@@ -0,0 +1,234 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"github.com/lib/pq"
+	"time"
+)
+
+// ArticleEngagement is what a published article received since some point
+// in time, which the trending worker scores.
+type ArticleEngagement struct {
+	ArticleID   string
+	PublishedAt time.Time
+	Likes       int
+	Comments    int
+	Saves       int
+	Views       int
+}
+
+// TrendingScore is a scored article ready to be stored for a period.
+type TrendingScore struct {
+	ArticleEngagement
+	Score float64
+}
+
+// TrendingArticle is a published article as listed on the trending tab.
+type TrendingArticle struct {
+	Article
+	Author       ArticleAuthor `json:"author"`
+	Score        float64       `json:"score"`
+	LikeCount    int           `json:"like_count"`
+	CommentCount int           `json:"comment_count"`
+	SaveCount    int           `json:"save_count"`
+	ViewCount    int           `json:"view_count"`
+	ComputedAt   time.Time     `json:"computed_at"`
+}
+
+type TrendingModel struct {
+	DB *sql.DB
+}
+
+// Engagement counts likes, comments, saves and views since the given time
+// for every published article that either received some or was published
+// after it.
+func (m *TrendingModel) Engagement(ctx context.Context, since time.Time) ([]*ArticleEngagement, error) {
+	const query = `
+	WITH likes AS (
+		SELECT article_id, count(*) AS n FROM liked_articles WHERE liked_at >= $1 GROUP BY article_id
+	), comments AS (
+		SELECT article_id, count(*) AS n FROM comments
+		WHERE created_at >= $1 AND deleted_at IS NULL
+		GROUP BY article_id
+	), saves AS (
+		SELECT article_id, count(*) AS n FROM saved_articles WHERE saved_at >= $1 GROUP BY article_id
+	), views AS (
+		SELECT article_id, count(*) AS n FROM article_views WHERE viewed_at >= $1 GROUP BY article_id
+	)
+	SELECT a.id, a.published_at,
+		COALESCE(l.n, 0), COALESCE(c.n, 0), COALESCE(s.n, 0), COALESCE(v.n, 0)
+	FROM articles a
+	LEFT JOIN likes l ON l.article_id = a.id
+	LEFT JOIN comments c ON c.article_id = a.id
+	LEFT JOIN saves s ON s.article_id = a.id
+	LEFT JOIN views v ON v.article_id = a.id
+	WHERE a.status = 'published'
+	AND a.published_at <= now()
+	AND (a.published_at >= $1 OR l.n IS NOT NULL OR c.n IS NOT NULL OR s.n IS NOT NULL OR v.n IS NOT NULL)
+	`
+	rows, err := m.DB.QueryContext(ctx, query, since)
+	if err != nil {
+		return nil, DetermineDBError(err, "trending_engagement")
+	}
+	defer rows.Close()
+
+	engagement := []*ArticleEngagement{}
+	for rows.Next() {
+		e := &ArticleEngagement{}
+		err = rows.Scan(
+			&e.ArticleID,
+			&e.PublishedAt,
+			&e.Likes,
+			&e.Comments,
+			&e.Saves,
+			&e.Views,
+		)
+		if err != nil {
+			return nil, DetermineDBError(err, "trending_engagement")
+		}
+		engagement = append(engagement, e)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, DetermineDBError(err, "trending_engagement")
+	}
+	return engagement, nil
+}
+
+// Replace swaps the stored scores for period with scores in one
+// transaction, so readers see either the old ranking or the new one.
+// Replicas refreshing the same period at once take turns.
+func (m *TrendingModel) Replace(ctx context.Context, period string, scores []TrendingScore) error {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return DetermineDBError(err, "trending_replace")
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext('trending_articles:' || $1))`, period)
+	if err != nil {
+		return DetermineDBError(err, "trending_replace")
+	}
+	_, err = tx.ExecContext(ctx, `DELETE FROM trending_articles WHERE period = $1`, period)
+	if err != nil {
+		return DetermineDBError(err, "trending_replace")
+	}
+
+	ids := make([]string, len(scores))
+	values := make([]float64, len(scores))
+	likes := make([]int64, len(scores))
+	comments := make([]int64, len(scores))
+	saves := make([]int64, len(scores))
+	views := make([]int64, len(scores))
+	for i, s := range scores {
+		ids[i] = s.ArticleID
+		values[i] = s.Score
+		likes[i] = int64(s.Likes)
+		comments[i] = int64(s.Comments)
+		saves[i] = int64(s.Saves)
+		views[i] = int64(s.Views)
+	}
+	const query = `
+	INSERT INTO trending_articles (period, article_id, score, likes, comments, saves, views)
+	SELECT $1, * FROM unnest($2::uuid[], $3::float8[], $4::int[], $5::int[], $6::int[], $7::int[])
+	`
+	_, err = tx.ExecContext(
+		ctx,
+		query,
+		period,
+		pq.Array(ids),
+		pq.Array(values),
+		pq.Array(likes),
+		pq.Array(comments),
+		pq.Array(saves),
+		pq.Array(views),
+	)
+	if err != nil {
+		return DetermineDBError(err, "trending_replace")
+	}
+	if err = tx.Commit(); err != nil {
+		return DetermineDBError(err, "trending_replace")
+	}
+	return nil
+}
+
+// GetAll lists the trending articles for period, highest score first,
+// optionally narrowed to a category by name.
+func (m *TrendingModel) GetAll(ctx context.Context, period, category string, filters Filters) ([]*TrendingArticle, Metadata, error) {
+	const query = `
+	SELECT count(*) OVER(), a.id, a.author_id, a.title, a.slug, a.content, a.status, a.version,
+		COALESCE(a.category_id, 0), COALESCE(c.name, ''),
+		COALESCE((
+			SELECT array_agg(t.name ORDER BY t.name)
+			FROM article_tags atg
+			JOIN tags t ON t.id = atg.tag_id
+			WHERE atg.article_id = a.id
+		), '{}'),
+		a.created_at, a.updated_at, a.published_at,
+		u.id, u.username, u.first_name, u.last_name, u.bio, u.profile_picture_url,
+		ta.score, ta.likes, ta.comments, ta.saves, ta.views, ta.computed_at
+	FROM trending_articles ta
+	JOIN articles a ON a.id = ta.article_id
+	JOIN users u ON u.id = a.author_id
+	LEFT JOIN categories c ON c.id = a.category_id
+	WHERE ta.period = $1
+	AND a.status = 'published'
+	AND (LOWER(c.name) = LOWER($2) OR $2 = '')
+	ORDER BY ta.score DESC, a.id
+	LIMIT $3 OFFSET $4
+	`
+	rows, err := m.DB.QueryContext(
+		ctx,
+		query,
+		period,
+		category,
+		filters.limit(),
+		filters.offset(),
+	)
+	if err != nil {
+		return nil, Metadata{}, DetermineDBError(err, "trending_getall")
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	articles := []*TrendingArticle{}
+	for rows.Next() {
+		article := &TrendingArticle{}
+		err = rows.Scan(
+			&totalRecords,
+			&article.ID,
+			&article.AuthorID,
+			&article.Title,
+			&article.Slug,
+			&article.Content,
+			&article.Status,
+			&article.Version,
+			&article.CategoryID,
+			&article.Category,
+			pq.Array(&article.Tags),
+			&article.CreatedAt,
+			&article.UpdatedAt,
+			&article.PublishedAt,
+			&article.Author.ID,
+			&article.Author.Username,
+			&article.Author.FirstName,
+			&article.Author.LastName,
+			&article.Author.Bio,
+			&article.Author.ProfilePicUrl,
+			&article.Score,
+			&article.LikeCount,
+			&article.CommentCount,
+			&article.SaveCount,
+			&article.ViewCount,
+			&article.ComputedAt,
+		)
+		if err != nil {
+			return nil, Metadata{}, DetermineDBError(err, "trending_getall")
+		}
+		articles = append(articles, article)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, DetermineDBError(err, "trending_getall")
+	}
+	return articles, calculateMetadata(totalRecords, filters.Page, filters.PageSize), nil
+}
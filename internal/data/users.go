@@ -3,6 +3,7 @@ package data
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"time"
 )
 
@@ -12,6 +13,7 @@ type UserModel struct {
 
 type User struct {
 	ID            string    `db:"id"`
+	Username      string    `db:"username"`
 	Email         string    `db:"email"`
 	Password      string    `db:"password"`
 	FirstName     string    `db:"first_name"`
@@ -20,15 +22,30 @@ type User struct {
 	ResetToken    *string   `db:"reset_token"`
 	LastName      string    `db:"last_name"`
 	Activated     bool      `db:"activated"`
+	Role          string    `db:"role"`
+	MFAEnabled    bool      `db:"totp_enabled"`
+	Version       int       `db:"version"`
 	CreatedAt     time.Time `db:"created_at"`
 	UpdatedAt     time.Time `db:"updated_at"`
 }
 
-func (m *UserModel) Create(ctx context.Context, user *User) (*User, error) {
+// UserSummary is the public slice of a user shown next to content they wrote.
+type UserSummary struct {
+	ID            string `json:"id"`
+	Username      string `json:"username"`
+	FirstName     string `json:"first_name"`
+	LastName      string `json:"last_name"`
+	ProfilePicUrl string `json:"profile_picture_url"`
+}
+
+// Create inserts the user together with their first activation token, so
+// an account is never left without a way to activate it. The returned
+// ActivationToken carries the plaintext so it can be emailed.
+func (m *UserModel) Create(ctx context.Context, user *User, activationTTL time.Duration) (*User, *ActivationToken, error) {
 	const query = `
-	INSERT INTO users (first_name, last_name, email, password_hash, bio, profile_picture_url)
-	VALUES ($1, $2, $3, $4, $5, $6)
-	RETURNING first_name, last_name , email, bio, profile_picture_url, created_at
+	INSERT INTO users (first_name, last_name, email, password_hash, bio, profile_picture_url, username)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)
+	RETURNING id, first_name, last_name , email, bio, profile_picture_url, username, role, created_at
 	`
 	if user.Bio == "" {
 		user.Bio = "Enter your bio"
@@ -36,8 +53,15 @@ func (m *UserModel) Create(ctx context.Context, user *User) (*User, error) {
 	if user.ProfilePicUrl == "" {
 		user.ProfilePicUrl = "https://placehold.co/400?text=U"
 	}
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, DetermineDBError(err, "user_create")
+	}
+	defer tx.Rollback()
+
 	newUser := &User{}
-	err := m.DB.QueryRowContext(
+	err = tx.QueryRowContext(
 		ctx,
 		query,
 		user.FirstName,
@@ -46,23 +70,34 @@ func (m *UserModel) Create(ctx context.Context, user *User) (*User, error) {
 		user.Password,
 		user.Bio,
 		user.ProfilePicUrl,
+		user.Username,
 	).Scan(
+		&newUser.ID,
 		&newUser.FirstName,
 		&newUser.LastName,
 		&newUser.Email,
 		&newUser.Bio,
 		&newUser.ProfilePicUrl,
+		&newUser.Username,
+		&newUser.Role,
 		&newUser.CreatedAt,
 	)
 	if err != nil {
-		return nil, DetermineDBError(err, "user_create")
+		return nil, nil, DetermineDBError(err, "user_create")
 	}
-	return newUser, nil
+	activationToken, err := replaceActivationToken(ctx, tx, newUser.ID, activationTTL)
+	if err != nil {
+		return nil, nil, DetermineDBError(err, "user_create")
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, nil, DetermineDBError(err, "user_create")
+	}
+	return newUser, activationToken, nil
 }
 
 func (m *UserModel) GetByEmail(ctx context.Context, email string) (*User, error) {
 	const query = `
-	SELECT first_name, last_name, email, id, password_hash, bio, profile_picture_url
+	SELECT first_name, last_name, email, id, username, password_hash, bio, profile_picture_url, activated, role, totp_enabled, version
 	FROM users
 	WHERE email = $1
 	`
@@ -76,9 +111,14 @@ func (m *UserModel) GetByEmail(ctx context.Context, email string) (*User, error)
 		&user.LastName,
 		&user.Email,
 		&user.ID,
+		&user.Username,
 		&user.Password,
 		&user.Bio,
 		&user.ProfilePicUrl,
+		&user.Activated,
+		&user.Role,
+		&user.MFAEnabled,
+		&user.Version,
 	)
 	if err != nil {
 		return nil, DetermineDBError(err, "user_findbyemail")
@@ -88,7 +128,7 @@ func (m *UserModel) GetByEmail(ctx context.Context, email string) (*User, error)
 
 func (m *UserModel) GetByID(ctx context.Context, id string) (*User, error) {
 	const query = `
-	SELECT first_name, last_name, email, id, password_hash, bio, profile_picture_url
+	SELECT first_name, last_name, email, id, username, password_hash, bio, profile_picture_url, activated, role, totp_enabled, version
 	FROM users
 	WHERE id = $1
 	`
@@ -103,9 +143,14 @@ func (m *UserModel) GetByID(ctx context.Context, id string) (*User, error) {
 		&user.LastName,
 		&user.Email,
 		&user.ID,
+		&user.Username,
 		&user.Password,
 		&user.Bio,
 		&user.ProfilePicUrl,
+		&user.Activated,
+		&user.Role,
+		&user.MFAEnabled,
+		&user.Version,
 	)
 	if err != nil {
 		return nil, DetermineDBError(err, "user_findbyemail")
@@ -113,6 +158,38 @@ func (m *UserModel) GetByID(ctx context.Context, id string) (*User, error) {
 	return user, nil
 }
 
+func (m *UserModel) GetByUsername(ctx context.Context, username string) (*User, error) {
+	const query = `
+	SELECT first_name, last_name, email, id, username, password_hash, bio, profile_picture_url, activated, role, totp_enabled, version
+	FROM users
+	WHERE username = $1
+	`
+
+	user := &User{}
+	err := m.DB.QueryRowContext(
+		ctx,
+		query,
+		username,
+	).Scan(
+		&user.FirstName,
+		&user.LastName,
+		&user.Email,
+		&user.ID,
+		&user.Username,
+		&user.Password,
+		&user.Bio,
+		&user.ProfilePicUrl,
+		&user.Activated,
+		&user.Role,
+		&user.MFAEnabled,
+		&user.Version,
+	)
+	if err != nil {
+		return nil, DetermineDBError(err, "user_findbyusername")
+	}
+	return user, nil
+}
+
 func (m *UserModel) UpdateDetails(ctx context.Context, user *User) (*User, error) {
 	const query = `
 	UPDATE users SET 
@@ -120,9 +197,11 @@ func (m *UserModel) UpdateDetails(ctx context.Context, user *User) (*User, error
 		last_name = $2, 
 		bio = $3, 
 		profile_picture_url = $4, 
-		activated = $5
-	WHERE id = $6
-	RETURNING id, first_name, last_name, bio, profile_picture_url, activated
+		activated = $5,
+		updated_at = now(),
+		version = version + 1
+	WHERE id = $6 AND version = $7
+	RETURNING id, first_name, last_name, bio, profile_picture_url, activated, version
 	`
 
 	updatedUser := &User{}
@@ -135,15 +214,26 @@ func (m *UserModel) UpdateDetails(ctx context.Context, user *User) (*User, error
 		user.ProfilePicUrl,
 		user.Activated,
 		user.ID,
+		user.Version,
 	).Scan(
 		&updatedUser.ID,
 		&updatedUser.FirstName,
 		&updatedUser.LastName,
 		&updatedUser.Bio,
 		&updatedUser.ProfilePicUrl,
-		&updatedUser.Activated)
+		&updatedUser.Activated,
+		&updatedUser.Version)
 
 	if err != nil {
+		// callers load the user before updating, so a missing row means the
+		// version changed underneath them rather than the user disappearing
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &DBError{
+				Err:       ErrEditConflict,
+				Operation: "user_updatedetails",
+				Detail:    "user was modified by another request",
+			}
+		}
 		return nil, DetermineDBError(err, "user_updatedetails")
 	}
 	return updatedUser, nil
@@ -181,6 +271,7 @@ func (m *UserModel) UpdatePassword(ctx context.Context, email, newPassword strin
 	UPDATE users 
 	SET password_hash = $1, updated_at = $2
 	WHERE email = $3
+	RETURNING id
 	`
 
 	data := &ModifiedData{}
@@ -200,7 +291,7 @@ func (m *UserModel) UpdatePassword(ctx context.Context, email, newPassword strin
 		return nil, DetermineDBError(err, "user_updatepassword")
 	}
 	data.Timestamp = updateTimestamp
-	return data, DetermineDBError(err, "user_updatepassword")
+	return data, nil
 }
 
 func (m *UserModel) Delete(ctx context.Context, id string) error {
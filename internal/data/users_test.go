@@ -0,0 +1,76 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	_ "github.com/lib/pq"
+	"os"
+	"testing"
+	"time"
+)
+
+// openTestDB connects to the migrated database named by TEST_DB_URL, and
+// skips the test when it isn't set.
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DB_URL")
+	if dsn == "" {
+		t.Skip("TEST_DB_URL not set")
+	}
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err = db.PingContext(ctx); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func createTestUser(t *testing.T, users *UserModel) *User {
+	t.Helper()
+	suffix := time.Now().UnixNano()
+	user, _, err := users.Create(context.Background(), &User{
+		Username:  fmt.Sprintf("test%d", suffix),
+		Email:     fmt.Sprintf("test%d@example.com", suffix),
+		Password:  "old-hash",
+		FirstName: "Test",
+		LastName:  "User",
+	}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { users.Delete(context.Background(), user.ID) })
+	return user
+}
+
+func TestUserModelUpdatePassword(t *testing.T) {
+	users := &UserModel{DB: openTestDB(t)}
+	user := createTestUser(t, users)
+	ctx := context.Background()
+
+	updated, err := users.UpdatePassword(ctx, user.Email, "new-hash")
+	if err != nil {
+		t.Fatalf("UpdatePassword: %v", err)
+	}
+	if updated.ID != user.ID {
+		t.Errorf("got id %s, want %s", updated.ID, user.ID)
+	}
+	stored, err := users.GetByEmail(ctx, user.Email)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Password != "new-hash" {
+		t.Errorf("got password hash %q, want new-hash", stored.Password)
+	}
+
+	_, err = users.UpdatePassword(ctx, "nobody-"+user.Email, "new-hash")
+	if !errors.Is(err, ErrRecordNotFound) {
+		t.Errorf("unknown email: got %v, want ErrRecordNotFound", err)
+	}
+}
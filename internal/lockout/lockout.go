@@ -0,0 +1,155 @@
+// Package lockout slows down and then locks out repeated failed attempts,
+// such as password guesses, keyed by account, IP address or anything else.
+package lockout
+
+import (
+	"context"
+	"time"
+)
+
+// Record is what a Store keeps for one key.
+type Record struct {
+	Failures    int
+	LastFailure time.Time
+	// PreviousFailure is the LastFailure before the latest one, kept so an
+	// attempt that is refunded can be undone without adding a delay.
+	PreviousFailure time.Time
+	LockedUntil     time.Time
+}
+
+// Store persists failure records. The in-memory store suits a single API
+// instance; replicas need a shared implementation.
+type Store interface {
+	Get(ctx context.Context, key string) (Record, error)
+	// Update applies fn to the key's current record and saves the result,
+	// atomically with respect to other updates of the same key.
+	Update(ctx context.Context, key string, fn func(Record) Record) (Record, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// Guard applies a backoff and lockout policy on top of a Store. After each
+// failure the key must wait BaseDelay, doubling per further failure up to
+// MaxDelay; after Threshold failures it is locked for LockoutDuration.
+// Failures older than Window are forgotten.
+type Guard struct {
+	Store           Store
+	Threshold       int
+	BaseDelay       time.Duration
+	MaxDelay        time.Duration
+	LockoutDuration time.Duration
+	Window          time.Duration
+	Now             func() time.Time
+}
+
+// Check returns how long the caller must wait before any of keys may try
+// again, or zero if all of them may try now.
+func (g *Guard) Check(ctx context.Context, keys ...string) (time.Duration, error) {
+	now := g.Now()
+	var wait time.Duration
+	for _, key := range keys {
+		record, err := g.Store.Get(ctx, key)
+		if err != nil {
+			return 0, err
+		}
+		if d := g.retryAt(record, now).Sub(now); d > wait {
+			wait = d
+		}
+	}
+	return wait, nil
+}
+
+// Fail records a failed attempt. locked is true only for the failure that
+// started a lockout, so callers can notify the owner exactly once.
+func (g *Guard) Fail(ctx context.Context, key string) (record Record, locked bool, err error) {
+	now := g.Now()
+	record, err = g.Store.Update(ctx, key, func(r Record) Record {
+		r, locked = g.fail(r, now)
+		return r
+	})
+	return record, locked, err
+}
+
+// Attempt checks key and, if it may try now, counts the attempt as a
+// failure before it is made, in the same Store update. Checking and then
+// failing separately lets concurrent guesses all pass the check before any
+// of them is counted. A non-zero wait means the attempt was refused and
+// nothing was counted; otherwise the caller Resets the key if the attempt
+// succeeds, or Refunds it with the returned record.
+func (g *Guard) Attempt(ctx context.Context, key string) (wait time.Duration, record Record, locked bool, err error) {
+	now := g.Now()
+	record, err = g.Store.Update(ctx, key, func(r Record) Record {
+		wait, locked = 0, false
+		if d := g.retryAt(r, now).Sub(now); d > 0 {
+			wait = d
+			return r
+		}
+		r, locked = g.fail(r, now)
+		return r
+	})
+	return wait, record, locked, err
+}
+
+// Refund takes back an attempt counted by Attempt that didn't fail but
+// shouldn't forget earlier failures either, such as a correct password
+// still waiting on its second factor. attempt is the record Attempt
+// returned.
+func (g *Guard) Refund(ctx context.Context, key string, attempt Record) error {
+	_, err := g.Store.Update(ctx, key, func(r Record) Record {
+		if r.Failures == 0 {
+			return r
+		}
+		r.Failures--
+		if r.LockedUntil.Equal(attempt.LastFailure.Add(g.LockoutDuration)) {
+			r.LockedUntil = time.Time{}
+		}
+		// a later attempt keeps its own delay
+		if r.LastFailure.Equal(attempt.LastFailure) {
+			r.LastFailure = attempt.PreviousFailure
+		}
+		return r
+	})
+	return err
+}
+
+// Reset forgets the failures for key, e.g. after a successful login.
+func (g *Guard) Reset(ctx context.Context, key string) error {
+	return g.Store.Delete(ctx, key)
+}
+
+func (g *Guard) fail(r Record, now time.Time) (Record, bool) {
+	if now.Sub(r.LastFailure) > g.Window && now.After(r.LockedUntil) {
+		r = Record{}
+	}
+	r.Failures++
+	r.PreviousFailure = r.LastFailure
+	r.LastFailure = now
+	if r.Failures >= g.Threshold && !now.Before(r.LockedUntil) {
+		r.LockedUntil = now.Add(g.LockoutDuration)
+		return r, true
+	}
+	return r, false
+}
+
+func (g *Guard) retryAt(r Record, now time.Time) time.Time {
+	if r.Failures == 0 {
+		return time.Time{}
+	}
+	if r.LockedUntil.After(r.LastFailure) {
+		return r.LockedUntil
+	}
+	if now.Sub(r.LastFailure) > g.Window {
+		return time.Time{}
+	}
+	return r.LastFailure.Add(g.delay(r.Failures))
+}
+
+func (g *Guard) delay(failures int) time.Duration {
+	d := g.BaseDelay
+	for i := 1; i < failures; i++ {
+		d *= 2
+		if d >= g.MaxDelay {
+			return g.MaxDelay
+		}
+	}
+	return d
+}
@@ -0,0 +1,279 @@
+package lockout
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeClock struct{ now time.Time }
+
+func (c *fakeClock) Now() time.Time          { return c.now }
+func (c *fakeClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func newGuard(clock *fakeClock) *Guard {
+	return &Guard{
+		Store:           NewMemoryStore(24 * time.Hour),
+		Threshold:       5,
+		BaseDelay:       time.Second,
+		MaxDelay:        8 * time.Second,
+		LockoutDuration: 15 * time.Minute,
+		Window:          time.Hour,
+		Now:             clock.Now,
+	}
+}
+
+func TestGuardBackoff(t *testing.T) {
+	ctx := context.Background()
+	clock := &fakeClock{now: time.Date(2024, 11, 1, 12, 0, 0, 0, time.UTC)}
+	guard := newGuard(clock)
+	guard.Threshold = 100
+
+	wants := []time.Duration{
+		time.Second,
+		2 * time.Second,
+		4 * time.Second,
+		8 * time.Second,
+		8 * time.Second,
+		8 * time.Second,
+	}
+	for i, want := range wants {
+		if _, _, err := guard.Fail(ctx, "key"); err != nil {
+			t.Fatal(err)
+		}
+		wait, err := guard.Check(ctx, "key")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if wait != want {
+			t.Errorf("after %d failures: got wait %v, want %v", i+1, wait, want)
+		}
+		clock.Advance(wait)
+		if wait, _ = guard.Check(ctx, "key"); wait != 0 {
+			t.Errorf("after %d failures: still waiting %v once the delay passed", i+1, wait)
+		}
+	}
+}
+
+func TestGuardLockout(t *testing.T) {
+	ctx := context.Background()
+	clock := &fakeClock{now: time.Date(2024, 11, 1, 12, 0, 0, 0, time.UTC)}
+	guard := newGuard(clock)
+
+	tests := []struct {
+		failure    int
+		wantLocked bool
+	}{
+		{1, false},
+		{2, false},
+		{3, false},
+		{4, false},
+		{5, true},
+		{6, false},
+		{7, false},
+	}
+	for _, tt := range tests {
+		record, locked, err := guard.Fail(ctx, "key")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if record.Failures != tt.failure {
+			t.Errorf("failure %d: record has %d failures", tt.failure, record.Failures)
+		}
+		if locked != tt.wantLocked {
+			t.Errorf("failure %d: got locked %v, want %v", tt.failure, locked, tt.wantLocked)
+		}
+		clock.Advance(time.Second)
+	}
+
+	wait, err := guard.Check(ctx, "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// locked at the fifth failure, three seconds ago
+	if want := 15*time.Minute - 3*time.Second; wait != want {
+		t.Errorf("got wait %v during the lockout, want %v", wait, want)
+	}
+
+	clock.Advance(wait)
+	if wait, _ = guard.Check(ctx, "key"); wait != 0 {
+		t.Errorf("still waiting %v after the lockout ended", wait)
+	}
+}
+
+func TestGuardWindow(t *testing.T) {
+	ctx := context.Background()
+	clock := &fakeClock{now: time.Date(2024, 11, 1, 12, 0, 0, 0, time.UTC)}
+	guard := newGuard(clock)
+
+	for i := 0; i < 3; i++ {
+		if _, _, err := guard.Fail(ctx, "key"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	clock.Advance(guard.Window + time.Second)
+	wait, err := guard.Check(ctx, "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wait != 0 {
+		t.Errorf("got wait %v after the window passed, want 0", wait)
+	}
+	record, _, err := guard.Fail(ctx, "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.Failures != 1 {
+		t.Errorf("got %d failures after the window passed, want 1", record.Failures)
+	}
+}
+
+func TestGuardReset(t *testing.T) {
+	ctx := context.Background()
+	clock := &fakeClock{now: time.Date(2024, 11, 1, 12, 0, 0, 0, time.UTC)}
+	guard := newGuard(clock)
+
+	for i := 0; i < guard.Threshold; i++ {
+		if _, _, err := guard.Fail(ctx, "key"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := guard.Reset(ctx, "key"); err != nil {
+		t.Fatal(err)
+	}
+	if wait, _ := guard.Check(ctx, "key"); wait != 0 {
+		t.Errorf("got wait %v after a reset, want 0", wait)
+	}
+}
+
+func TestGuardCheckTakesLongestWait(t *testing.T) {
+	ctx := context.Background()
+	clock := &fakeClock{now: time.Date(2024, 11, 1, 12, 0, 0, 0, time.UTC)}
+	guard := newGuard(clock)
+
+	for i := 0; i < 3; i++ {
+		if _, _, err := guard.Fail(ctx, "account"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, _, err := guard.Fail(ctx, "ip"); err != nil {
+		t.Fatal(err)
+	}
+	wait, err := guard.Check(ctx, "ip", "account", "unknown")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wait != 4*time.Second {
+		t.Errorf("got wait %v, want the account's 4s", wait)
+	}
+}
+
+func TestGuardAttemptConcurrent(t *testing.T) {
+	tests := []struct {
+		name        string
+		baseDelay   time.Duration
+		wantAllowed int
+	}{
+		// the first attempt's backoff holds off every other one
+		{"with backoff", time.Second, 1},
+		// without a per-try delay, attempts pass until the lockout starts
+		{"without backoff", 0, 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			clock := &fakeClock{now: time.Date(2024, 11, 1, 12, 0, 0, 0, time.UTC)}
+			guard := newGuard(clock)
+			guard.BaseDelay = tt.baseDelay
+
+			var (
+				wg      sync.WaitGroup
+				mu      sync.Mutex
+				allowed int
+				locks   int
+			)
+			for i := 0; i < 50; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					wait, _, locked, err := guard.Attempt(ctx, "key")
+					if err != nil {
+						t.Error(err)
+						return
+					}
+					mu.Lock()
+					defer mu.Unlock()
+					if wait == 0 {
+						allowed++
+					}
+					if locked {
+						locks++
+					}
+				}()
+			}
+			wg.Wait()
+
+			if allowed != tt.wantAllowed {
+				t.Errorf("%d concurrent attempts got through, want %d", allowed, tt.wantAllowed)
+			}
+			record, err := guard.Store.Get(ctx, "key")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if record.Failures != tt.wantAllowed {
+				t.Errorf("record has %d failures, want %d", record.Failures, tt.wantAllowed)
+			}
+			if wantLocks := tt.wantAllowed / guard.Threshold; locks != wantLocks {
+				t.Errorf("got %d lockouts, want %d", locks, wantLocks)
+			}
+		})
+	}
+}
+
+func TestGuardRefund(t *testing.T) {
+	ctx := context.Background()
+	clock := &fakeClock{now: time.Date(2024, 11, 1, 12, 0, 0, 0, time.UTC)}
+	guard := newGuard(clock)
+
+	tests := []struct {
+		name         string
+		priorFailure int
+	}{
+		{"no earlier failures", 0},
+		{"earlier failures", 2},
+		{"attempt that started the lockout", guard.Threshold - 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := tt.name
+			for i := 0; i < tt.priorFailure; i++ {
+				if _, _, err := guard.Fail(ctx, key); err != nil {
+					t.Fatal(err)
+				}
+				clock.Advance(time.Minute)
+			}
+			wait, attempt, _, err := guard.Attempt(ctx, key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if wait != 0 {
+				t.Fatalf("attempt refused with wait %v", wait)
+			}
+			if err = guard.Refund(ctx, key, attempt); err != nil {
+				t.Fatal(err)
+			}
+
+			record, err := guard.Store.Get(ctx, key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if record.Failures != tt.priorFailure {
+				t.Errorf("got %d failures after the refund, want %d", record.Failures, tt.priorFailure)
+			}
+			if wait, _ = guard.Check(ctx, key); wait != 0 {
+				t.Errorf("got wait %v after the refund, want 0", wait)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package lockout
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepEvery is how many updates pass between sweeps for stale records.
+const sweepEvery = 1000
+
+type MemoryStore struct {
+	mu      sync.Mutex
+	records map[string]Record
+	updates int
+	// ttl is how long a record is kept after its last failure or lockout.
+	ttl time.Duration
+}
+
+// NewMemoryStore keeps records in process, dropping them ttl after they
+// last mattered.
+func NewMemoryStore(ttl time.Duration) *MemoryStore {
+	return &MemoryStore{records: make(map[string]Record), ttl: ttl}
+}
+
+func (s *MemoryStore) Get(ctx context.Context, key string) (Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.records[key], nil
+}
+
+func (s *MemoryStore) Update(ctx context.Context, key string, fn func(Record) Record) (Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record := fn(s.records[key])
+	s.records[key] = record
+	s.updates++
+	if s.updates%sweepEvery == 0 {
+		s.sweep(time.Now())
+	}
+	return record, nil
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, key)
+	return nil
+}
+
+func (s *MemoryStore) sweep(now time.Time) {
+	for key, record := range s.records {
+		last := record.LastFailure
+		if record.LockedUntil.After(last) {
+			last = record.LockedUntil
+		}
+		if now.Sub(last) > s.ttl {
+			delete(s.records, key)
+		}
+	}
+}
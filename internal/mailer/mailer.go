@@ -1 +1,152 @@
 package mailer
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"strings"
+	ttemplate "text/template"
+	"time"
+)
+
+//go:embed "templates"
+var templateFS embed.FS
+
+const (
+	defaultAttempts = 3
+	defaultBackoff  = 500 * time.Millisecond
+)
+
+// Mailer renders one of the embedded templates and delivers it to recipient.
+// templateFile names a file under templates/, which must define "subject",
+// "plainBody" and "htmlBody".
+type Mailer interface {
+	Send(ctx context.Context, recipient, templateFile string, data any) error
+}
+
+type message struct {
+	sender    string
+	recipient string
+	subject   string
+	plainBody string
+	htmlBody  string
+}
+
+func render(sender, recipient, templateFile string, data any) (*message, error) {
+	path := "templates/" + templateFile
+	textTmpl, err := ttemplate.New("email").ParseFS(templateFS, path)
+	if err != nil {
+		return nil, err
+	}
+	msg := &message{sender: sender, recipient: recipient}
+
+	var buf bytes.Buffer
+	if err = textTmpl.ExecuteTemplate(&buf, "subject", data); err != nil {
+		return nil, err
+	}
+	msg.subject = strings.TrimSpace(buf.String())
+
+	buf.Reset()
+	if err = textTmpl.ExecuteTemplate(&buf, "plainBody", data); err != nil {
+		return nil, err
+	}
+	msg.plainBody = buf.String()
+
+	htmlTmpl, err := template.New("email").ParseFS(templateFS, path)
+	if err != nil {
+		return nil, err
+	}
+	buf.Reset()
+	if err = htmlTmpl.ExecuteTemplate(&buf, "htmlBody", data); err != nil {
+		return nil, err
+	}
+	msg.htmlBody = buf.String()
+	return msg, nil
+}
+
+// bytes encodes the message as a multipart/alternative RFC 5322 email.
+func (msg *message) bytes() ([]byte, error) {
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+	for _, part := range []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=utf-8", msg.plainBody},
+		{"text/html; charset=utf-8", msg.htmlBody},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		if _, err = qp.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+		if err = qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	headers := []struct{ key, value string }{
+		{"From", msg.sender},
+		{"To", msg.recipient},
+		{"Subject", mime.QEncoding.Encode("utf-8", msg.subject)},
+		{"Date", time.Now().UTC().Format(time.RFC1123Z)},
+		{"Message-ID", messageID(msg.sender)},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", fmt.Sprintf("multipart/alternative; boundary=%q", parts.Boundary())},
+	}
+	for _, header := range headers {
+		fmt.Fprintf(&out, "%s: %s\r\n", header.key, header.value)
+	}
+	out.WriteString("\r\n")
+	out.Write(body.Bytes())
+	return out.Bytes(), nil
+}
+
+func messageID(sender string) string {
+	domain := "localhost"
+	if i := strings.LastIndex(sender, "@"); i != -1 {
+		domain = strings.Trim(sender[i+1:], "> ")
+	}
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return fmt.Sprintf("<%s@%s>", hex.EncodeToString(b), domain)
+}
+
+// withRetry calls deliver until it succeeds, the attempts run out or ctx is
+// done, doubling the wait between attempts.
+func withRetry(ctx context.Context, attempts int, backoff time.Duration, deliver func() error) error {
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if err = deliver(); err == nil {
+			return nil
+		}
+		if attempt == attempts {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w (last delivery error: %v)", ctx.Err(), err)
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+	return fmt.Errorf("mail delivery failed after %d attempts: %w", attempts, err)
+}
@@ -0,0 +1,143 @@
+package mailer
+
+import (
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testSender = "65ch <no-reply@65ch.local>"
+
+var welcomeData = map[string]any{
+	"firstName":     "Ada <b>",
+	"username":      "ada",
+	"activationURL": "https://65ch.local/activate?token=abc123",
+	"expiresIn":     "72h0m0s",
+}
+
+// readMessage parses a raw email and returns its headers and the decoded
+// plain and HTML parts.
+func readMessage(t *testing.T, r io.Reader) (mail.Header, map[string]string) {
+	t.Helper()
+	msg, err := mail.ReadMessage(r)
+	if err != nil {
+		t.Fatalf("message doesn't parse: %v", err)
+	}
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mediaType != "multipart/alternative" {
+		t.Fatalf("got Content-Type %s, want multipart/alternative", mediaType)
+	}
+	parts := make(map[string]string)
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(part)
+		if err != nil {
+			t.Fatal(err)
+		}
+		contentType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		parts[contentType] = string(body)
+	}
+	return msg.Header, parts
+}
+
+func TestRender(t *testing.T) {
+	msg, err := render(testSender, "ada@example.com", "welcome.tmpl", welcomeData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := msg.bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	header, parts := readMessage(t, strings.NewReader(string(raw)))
+
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"From", testSender},
+		{"To", "ada@example.com"},
+		{"MIME-Version", "1.0"},
+	}
+	for _, tt := range tests {
+		if got := header.Get(tt.header); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.header, got, tt.want)
+		}
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(header.Get("Subject"))
+	if err != nil || subject != "Welcome to 65ch!" {
+		t.Errorf("got Subject %q (%v), want %q", subject, err, "Welcome to 65ch!")
+	}
+	if _, err = header.Date(); err != nil {
+		t.Errorf("Date header: %v", err)
+	}
+	if id := header.Get("Message-ID"); !strings.HasSuffix(id, "@65ch.local>") {
+		t.Errorf("got Message-ID %q, want one at the sender's domain", id)
+	}
+
+	plain := parts["text/plain"]
+	if !strings.Contains(plain, "Hi Ada <b>,") || !strings.Contains(plain, welcomeData["activationURL"].(string)) {
+		t.Errorf("plain part is missing the template data:\n%s", plain)
+	}
+	html := parts["text/html"]
+	if !strings.Contains(html, "Ada &lt;b&gt;") {
+		t.Errorf("html part doesn't escape the template data:\n%s", html)
+	}
+}
+
+func TestRenderUnknownTemplate(t *testing.T) {
+	if _, err := render(testSender, "ada@example.com", "missing.tmpl", nil); err == nil {
+		t.Error("rendered a template that doesn't exist")
+	}
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	m, err := NewFileMailer(dir, testSender)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = m.Send(context.Background(), "ada+test@example.com", "welcome.tmpl", welcomeData); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("got %d files, want 1", len(files))
+	}
+	name := files[0].Name()
+	if !strings.HasSuffix(name, "-ada_test@example.com.eml") {
+		t.Errorf("got file name %q", name)
+	}
+	f, err := os.Open(filepath.Join(dir, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	header, parts := readMessage(t, f)
+	if got := header.Get("To"); got != "ada+test@example.com" {
+		t.Errorf("got To %q", got)
+	}
+	if !strings.Contains(parts["text/plain"], welcomeData["activationURL"].(string)) {
+		t.Errorf("plain part is missing the activation link:\n%s", parts["text/plain"])
+	}
+}
@@ -0,0 +1,104 @@
+package mailer
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"time"
+)
+
+// SMTPMailer delivers mail through an SMTP relay, upgrading to TLS when the
+// server offers STARTTLS.
+type SMTPMailer struct {
+	host     string
+	port     int
+	username string
+	password string
+	// sender is the From header, which may carry a display name;
+	// envelopeSender is just its address, for MAIL FROM.
+	sender         string
+	envelopeSender string
+	attempts       int
+	backoff        time.Duration
+}
+
+func NewSMTPMailer(host string, port int, username, password, sender string) (*SMTPMailer, error) {
+	addr, err := mail.ParseAddress(sender)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mail sender %q: %w", sender, err)
+	}
+	return &SMTPMailer{
+		host:           host,
+		port:           port,
+		username:       username,
+		password:       password,
+		sender:         sender,
+		envelopeSender: addr.Address,
+		attempts:       defaultAttempts,
+		backoff:        defaultBackoff,
+	}, nil
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, recipient, templateFile string, data any) error {
+	msg, err := render(m.sender, recipient, templateFile, data)
+	if err != nil {
+		return err
+	}
+	raw, err := msg.bytes()
+	if err != nil {
+		return err
+	}
+	return withRetry(ctx, m.attempts, m.backoff, func() error {
+		return m.deliver(ctx, recipient, raw)
+	})
+}
+
+func (m *SMTPMailer) deliver(ctx context.Context, recipient string, raw []byte) error {
+	dialer := net.Dialer{Timeout: 10 * time.Second}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(m.host, strconv.Itoa(m.port)))
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, m.host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err = client.StartTLS(&tls.Config{ServerName: m.host}); err != nil {
+			return err
+		}
+	}
+	if m.username != "" {
+		if err = client.Auth(smtp.PlainAuth("", m.username, m.password, m.host)); err != nil {
+			return err
+		}
+	}
+	if err = client.Mail(m.envelopeSender); err != nil {
+		return err
+	}
+	if err = client.Rcpt(recipient); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write(raw); err != nil {
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
@@ -0,0 +1,141 @@
+package mailer
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"testing"
+)
+
+// fakeSMTPServer accepts one plain-text SMTP session and records the
+// envelope and the message it was given.
+type fakeSMTPServer struct {
+	listener net.Listener
+	from     string
+	to       []string
+	data     string
+	done     chan struct{}
+}
+
+func newFakeSMTPServer(t *testing.T) *fakeSMTPServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("can't listen on localhost: %v", err)
+	}
+	s := &fakeSMTPServer{listener: listener, done: make(chan struct{})}
+	go s.serve()
+	t.Cleanup(func() { listener.Close() })
+	return s
+}
+
+func (s *fakeSMTPServer) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *fakeSMTPServer) serve() {
+	defer close(s.done)
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+	reply("220 localhost ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		command := strings.ToUpper(line)
+		switch {
+		case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(command, "MAIL FROM:"):
+			s.from = line[len("MAIL FROM:"):]
+			reply("250 OK")
+		case strings.HasPrefix(command, "RCPT TO:"):
+			s.to = append(s.to, line[len("RCPT TO:"):])
+			reply("250 OK")
+		case command == "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(line)
+			}
+			s.data = data.String()
+			reply("250 OK")
+		case command == "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
+
+func TestSMTPMailerSend(t *testing.T) {
+	server := newFakeSMTPServer(t)
+	m, err := NewSMTPMailer("127.0.0.1", server.port(), "", "", testSender)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.attempts = 1
+	if err = m.Send(context.Background(), "ada@example.com", "welcome.tmpl", welcomeData); err != nil {
+		t.Fatal(err)
+	}
+	<-server.done
+
+	// the envelope carries bare addresses, the headers the display form
+	if server.from != "<no-reply@65ch.local>" {
+		t.Errorf("got MAIL FROM:%s, want <no-reply@65ch.local>", server.from)
+	}
+	if len(server.to) != 1 || server.to[0] != "<ada@example.com>" {
+		t.Errorf("got RCPT TO %q, want <ada@example.com>", server.to)
+	}
+	header, parts := readMessage(t, strings.NewReader(server.data))
+	if got := header.Get("From"); got != testSender {
+		t.Errorf("got From %q, want %q", got, testSender)
+	}
+	if got := header.Get("To"); got != "ada@example.com" {
+		t.Errorf("got To %q, want ada@example.com", got)
+	}
+	if !strings.Contains(parts["text/plain"], welcomeData["activationURL"].(string)) {
+		t.Errorf("plain part is missing the activation link:\n%s", parts["text/plain"])
+	}
+}
+
+func TestNewSMTPMailerSender(t *testing.T) {
+	tests := []struct {
+		sender   string
+		envelope string
+		wantErr  bool
+	}{
+		{"65ch <no-reply@65ch.local>", "no-reply@65ch.local", false},
+		{"no-reply@65ch.local", "no-reply@65ch.local", false},
+		{`"65ch, the blog" <hello@65ch.local>`, "hello@65ch.local", false},
+		{"65ch", "", true},
+		{"", "", true},
+	}
+	for _, tt := range tests {
+		m, err := NewSMTPMailer("localhost", 25, "", "", tt.sender)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%q: got error %v, want error %v", tt.sender, err, tt.wantErr)
+			continue
+		}
+		if err == nil && m.envelopeSender != tt.envelope {
+			t.Errorf("%q: got envelope sender %q, want %q", tt.sender, m.envelopeSender, tt.envelope)
+		}
+	}
+}
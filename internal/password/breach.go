@@ -0,0 +1,148 @@
+package password
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// BreachChecker reports how many times a password appears in known
+// breaches, zero when it doesn't.
+type BreachChecker interface {
+	Breached(ctx context.Context, password string) (int, error)
+}
+
+// NewBreachCorpus opens an offline copy of a breached password corpus in
+// the Have I Been Pwned layout: uppercase SHA-1 hashes with a count, either
+// as one file sorted by hash ("HASH:COUNT" per line) or as a directory of
+// range files named by 5-character hash prefix ("ABCDE.txt", holding
+// "SUFFIX:COUNT" lines).
+func NewBreachCorpus(path string) (BreachChecker, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return rangeDirectory(path), nil
+	}
+	return sortedHashFile(path), nil
+}
+
+func sha1Hex(password string) string {
+	sum := sha1.Sum([]byte(password))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+// rangeDirectory holds one file per hash prefix, the same k-anonymity
+// ranges the online API serves.
+type rangeDirectory string
+
+func (d rangeDirectory) Breached(ctx context.Context, password string) (int, error) {
+	hash := sha1Hex(password)
+	prefix, suffix := hash[:5], hash[5:]
+	f, err := os.Open(filepath.Join(string(d), prefix+".txt"))
+	if errors.Is(err, os.ErrNotExist) {
+		f, err = os.Open(filepath.Join(string(d), prefix))
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if count, ok := matchLine(scanner.Text(), suffix); ok {
+			return count, nil
+		}
+	}
+	return 0, scanner.Err()
+}
+
+// sortedHashFile is the whole corpus in one file, too big to load, so it
+// is binary searched on disk.
+type sortedHashFile string
+
+func (p sortedHashFile) Breached(ctx context.Context, password string) (int, error) {
+	hash := sha1Hex(password)
+	f, err := os.Open(string(p))
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	// find the first line that starts at or after an offset whose hash is
+	// not less than ours
+	low, high := int64(0), info.Size()
+	for low < high {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		mid := low + (high-low)/2
+		line, err := lineAfter(f, mid)
+		if err != nil {
+			return 0, err
+		}
+		if line != "" && strings.ToUpper(line[:min(len(line), len(hash))]) < hash {
+			low = mid + 1
+		} else {
+			high = mid
+		}
+	}
+	line, err := lineAfter(f, low)
+	if err != nil {
+		return 0, err
+	}
+	if count, ok := matchLine(line, hash); ok {
+		return count, nil
+	}
+	return 0, nil
+}
+
+// lineAfter returns the first full line starting at or after offset, or
+// the first line in the file for offset 0.
+func lineAfter(f *os.File, offset int64) (string, error) {
+	start := offset
+	if offset > 0 {
+		start--
+	}
+	reader := bufio.NewReader(io.NewSectionReader(f, start, 1<<62))
+	if offset > 0 {
+		if _, err := reader.ReadString('\n'); err != nil {
+			if errors.Is(err, io.EOF) {
+				return "", nil
+			}
+			return "", err
+		}
+	}
+	line, err := reader.ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
+
+func matchLine(line, hash string) (int, bool) {
+	found, countText, ok := strings.Cut(strings.TrimSpace(line), ":")
+	if !ok || !strings.EqualFold(found, hash) {
+		return 0, false
+	}
+	count, err := strconv.Atoi(countText)
+	if err != nil {
+		return 1, true
+	}
+	return count, true
+}
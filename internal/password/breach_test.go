@@ -0,0 +1,160 @@
+package password
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// corpus is a tiny breach corpus, written out in both layouts by the tests.
+var corpus = map[string]int{
+	"password": 9545824,
+	"123456":   37359195,
+	"letmein":  1301720,
+	"dragon":   1002654,
+	"hunter2":  17043,
+	"trustno1": 145438,
+	"iloveyou": 2330,
+}
+
+// writeSortedCorpus writes corpus as one file sorted by hash, and returns
+// the passwords in file order.
+func writeSortedCorpus(t *testing.T, trailingNewline bool) (string, []string) {
+	t.Helper()
+	passwords := make([]string, 0, len(corpus))
+	for password := range corpus {
+		passwords = append(passwords, password)
+	}
+	sort.Slice(passwords, func(i, j int) bool { return sha1Hex(passwords[i]) < sha1Hex(passwords[j]) })
+	lines := make([]string, len(passwords))
+	for i, password := range passwords {
+		lines[i] = sha1Hex(password) + ":" + strconv.Itoa(corpus[password])
+	}
+	contents := strings.Join(lines, "\n")
+	if trailingNewline {
+		contents += "\n"
+	}
+	path := filepath.Join(t.TempDir(), "pwned-passwords-sha1-ordered-by-hash.txt")
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path, passwords
+}
+
+func TestSortedHashFile(t *testing.T) {
+	for _, trailingNewline := range []bool{true, false} {
+		path, passwords := writeSortedCorpus(t, trailingNewline)
+		corpusFile := sortedHashFile(path)
+		tests := []struct {
+			name     string
+			password string
+			want     int
+		}{
+			{"first line", passwords[0], corpus[passwords[0]]},
+			{"middle line", passwords[len(passwords)/2], corpus[passwords[len(passwords)/2]]},
+			{"last line", passwords[len(passwords)-1], corpus[passwords[len(passwords)-1]]},
+			{"missing", "x7#Kq9!mZ2@w", 0},
+			{"prefix of a breached password", "passwor", 0},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name+" trailing newline "+strconv.FormatBool(trailingNewline), func(t *testing.T) {
+				got, err := corpusFile.Breached(context.Background(), tt.password)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got != tt.want {
+					t.Errorf("got %d, want %d", got, tt.want)
+				}
+			})
+		}
+	}
+}
+
+func TestSortedHashFileFindsEveryEntry(t *testing.T) {
+	path, _ := writeSortedCorpus(t, true)
+	for password, want := range corpus {
+		got, err := sortedHashFile(path).Breached(context.Background(), password)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("%s: got %d, want %d", password, got, want)
+		}
+	}
+}
+
+func TestRangeDirectory(t *testing.T) {
+	dir := t.TempDir()
+	ranges := make(map[string][]string)
+	for password, count := range corpus {
+		hash := sha1Hex(password)
+		ranges[hash[:5]] = append(ranges[hash[:5]], hash[5:]+":"+strconv.Itoa(count))
+	}
+	for prefix, lines := range ranges {
+		name := prefix + ".txt"
+		// range files are also accepted without the extension
+		if prefix == sha1Hex("dragon")[:5] {
+			name = prefix
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(strings.Join(lines, "\r\n")), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		password string
+		want     int
+	}{
+		{"breached", "password", corpus["password"]},
+		{"range file without extension", "dragon", corpus["dragon"]},
+		{"missing range file", "x7#Kq9!mZ2@w", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := rangeDirectory(dir).Breached(context.Background(), tt.password)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %d, want %d", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("missing suffix in an existing range", func(t *testing.T) {
+		prefix := sha1Hex("hunter2")[:5]
+		other := "0000000000000000000000000000000000:5"
+		if err := os.WriteFile(filepath.Join(dir, prefix+".txt"), []byte(other), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		got, err := rangeDirectory(dir).Breached(context.Background(), "hunter2")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != 0 {
+			t.Errorf("got %d, want 0", got)
+		}
+	})
+}
+
+func TestNewBreachCorpus(t *testing.T) {
+	path, _ := writeSortedCorpus(t, true)
+	if checker, err := NewBreachCorpus(path); err != nil {
+		t.Fatal(err)
+	} else if _, ok := checker.(sortedHashFile); !ok {
+		t.Errorf("a file opened as %T", checker)
+	}
+	if checker, err := NewBreachCorpus(filepath.Dir(path)); err != nil {
+		t.Fatal(err)
+	} else if _, ok := checker.(rangeDirectory); !ok {
+		t.Errorf("a directory opened as %T", checker)
+	}
+	if _, err := NewBreachCorpus(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("a missing corpus opened without error")
+	}
+}
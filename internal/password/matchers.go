@@ -0,0 +1,344 @@
+package password
+
+import (
+	_ "embed"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+//go:embed common.txt
+var commonPasswords string
+
+//go:embed words.txt
+var commonWords string
+
+// dictionaries map each entry to its rank, which is how many guesses an
+// attacker working down the list needs to reach it. The lists are short:
+// l33t spellings and reversals of their words are caught, but a word missing
+// from both is priced as brute force however common it is, so the breach
+// corpus is what catches the long tail.
+var dictionaries = []map[string]int{
+	rankedDictionary(commonPasswords),
+	rankedDictionary(commonWords),
+}
+
+func rankedDictionary(list string) map[string]int {
+	ranked := make(map[string]int)
+	for i, word := range strings.Fields(list) {
+		if _, ok := ranked[word]; !ok {
+			ranked[word] = i + 1
+		}
+	}
+	return ranked
+}
+
+var leetSubstitutions = []map[rune]rune{
+	{'4': 'a', '@': 'a', '8': 'b', '(': 'c', '3': 'e', '6': 'g', '1': 'i', '!': 'i', '0': 'o', '$': 's', '5': 's', '7': 't', '+': 't', '2': 'z'},
+	{'4': 'a', '@': 'a', '8': 'b', '(': 'c', '3': 'e', '6': 'g', '1': 'l', '|': 'l', '0': 'o', '$': 's', '5': 's', '7': 't', '+': 't', '2': 'z'},
+}
+
+func dictionaryMatches(runes []rune) []match {
+	lower := []rune(strings.ToLower(string(runes)))
+	variants := [][]rune{lower}
+	for _, table := range leetSubstitutions {
+		variants = append(variants, unleet(lower, table))
+	}
+
+	var matches []match
+	n := len(runes)
+	for i := 0; i < n; i++ {
+		for j := i + 2; j < n; j++ {
+			original := runes[i : j+1]
+			for v, variant := range variants {
+				word := string(variant[i : j+1])
+				if v > 0 && word == string(lower[i:j+1]) {
+					continue
+				}
+				multiplier := uppercaseVariations(original)
+				if v > 0 {
+					multiplier *= 2
+				}
+				if rank, ok := lookup(word); ok {
+					matches = append(matches, match{i: i, j: j, guesses: float64(rank) * multiplier, pattern: patternDictionary})
+				}
+				if rank, ok := lookup(reverse(word)); ok && word != reverse(word) {
+					matches = append(matches, match{i: i, j: j, guesses: float64(rank) * multiplier * 2, pattern: patternDictionary})
+				}
+			}
+		}
+	}
+	return matches
+}
+
+func lookup(word string) (int, bool) {
+	best, found := 0, false
+	for _, dictionary := range dictionaries {
+		if rank, ok := dictionary[word]; ok && (!found || rank < best) {
+			best, found = rank, true
+		}
+	}
+	return best, found
+}
+
+func unleet(runes []rune, table map[rune]rune) []rune {
+	out := make([]rune, len(runes))
+	for i, r := range runes {
+		if sub, ok := table[r]; ok {
+			out[i] = sub
+		} else {
+			out[i] = r
+		}
+	}
+	return out
+}
+
+func reverse(s string) string {
+	runes := []rune(s)
+	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+		runes[i], runes[j] = runes[j], runes[i]
+	}
+	return string(runes)
+}
+
+// uppercaseVariations is how many ways of capitalising a word an attacker
+// tries before reaching this one. Capitalising the first or last letter, or
+// all of them, is so common that it barely counts.
+func uppercaseVariations(runes []rune) float64 {
+	upper, lower := 0, 0
+	for _, r := range runes {
+		if unicode.IsUpper(r) {
+			upper++
+		} else if unicode.IsLower(r) {
+			lower++
+		}
+	}
+	if upper == 0 {
+		return 1
+	}
+	if lower == 0 || (upper == 1 && (unicode.IsUpper(runes[0]) || unicode.IsUpper(runes[len(runes)-1]))) {
+		return 2
+	}
+	variations := 0.0
+	for i := 1; i <= min(upper, lower); i++ {
+		variations += binomial(upper+lower, i)
+	}
+	return variations
+}
+
+func binomial(n, k int) float64 {
+	result := 1.0
+	for i := 1; i <= k; i++ {
+		result = result * float64(n-k+i) / float64(i)
+	}
+	return result
+}
+
+var keyboardRows = []string{
+	"`1234567890-=",
+	"qwertyuiop[]\\",
+	"asdfghjkl;'",
+	"zxcvbnm,./",
+	"qazwsxedcrfvtgbyhnujmikolp",
+	"1qaz2wsx3edc4rfv5tgb6yhn7ujm8ik9ol0p",
+}
+
+const (
+	keyboardStartingPositions = 94
+	keyboardAverageDegree     = 4.6
+)
+
+// spatialMatches finds runs of neighbouring keys, along a row or down the
+// columns.
+func spatialMatches(runes []rune) []match {
+	lower := strings.ToLower(string(runes))
+	n := len(runes)
+	var matches []match
+	for i := 0; i < n; i++ {
+		for j := n - 1; j >= i+2; j-- {
+			run := string([]rune(lower)[i : j+1])
+			if onKeyboard(run) {
+				length := float64(j - i + 1)
+				matches = append(matches, match{i: i, j: j, guesses: keyboardStartingPositions * keyboardAverageDegree * (length - 1), pattern: patternSpatial})
+				break
+			}
+		}
+	}
+	return matches
+}
+
+func onKeyboard(run string) bool {
+	for _, row := range keyboardRows {
+		if strings.Contains(row, run) || strings.Contains(row, reverse(run)) {
+			return true
+		}
+	}
+	return false
+}
+
+// repeatMatches finds a character or block repeated back to back, priced
+// as guessing the block and then how many times it repeats.
+func repeatMatches(runes []rune) []match {
+	n := len(runes)
+	var matches []match
+	for i := 0; i < n; {
+		longest, block := 0, 0
+		for size := 1; i+2*size <= n; size++ {
+			count := 1
+			for i+(count+1)*size <= n && string(runes[i+count*size:i+(count+1)*size]) == string(runes[i:i+size]) {
+				count++
+			}
+			if count > 1 && count*size > longest && (size > 1 || count > 2) {
+				longest, block = count*size, size
+			}
+		}
+		if longest == 0 {
+			i++
+			continue
+		}
+		base := Strength(string(runes[i : i+block])).Guesses
+		matches = append(matches, match{i: i, j: i + longest - 1, guesses: base * float64(longest/block), pattern: patternRepeat})
+		i += longest
+	}
+	return matches
+}
+
+// sequenceMatches finds runs like "abc", "9753" or "zyx" with a steady step
+// between characters of the same kind.
+func sequenceMatches(runes []rune) []match {
+	n := len(runes)
+	var matches []match
+	for i := 0; i < n-2; {
+		delta := runes[i+1] - runes[i]
+		if delta == 0 || delta > 5 || delta < -5 || !sameClass(runes[i], runes[i+1]) {
+			i++
+			continue
+		}
+		j := i + 1
+		for j+1 < n && runes[j+1]-runes[j] == delta && sameClass(runes[j], runes[j+1]) {
+			j++
+		}
+		if j-i+1 < 3 {
+			i++
+			continue
+		}
+		matches = append(matches, match{i: i, j: j, guesses: sequenceGuesses(runes[i], delta, j-i+1), pattern: patternSequence})
+		i = j + 1
+	}
+	return matches
+}
+
+func sequenceGuesses(first rune, delta rune, length int) float64 {
+	base := 26.0
+	switch {
+	case strings.ContainsRune("aAzZ019", first):
+		base = 4
+	case unicode.IsDigit(first):
+		base = 10
+	}
+	if delta < 0 {
+		base *= 2
+	}
+	return base * float64(length)
+}
+
+func sameClass(a, b rune) bool {
+	return (unicode.IsLower(a) && unicode.IsLower(b)) ||
+		(unicode.IsUpper(a) && unicode.IsUpper(b)) ||
+		(unicode.IsDigit(a) && unicode.IsDigit(b))
+}
+
+const minYearSpace = 20
+
+var (
+	yearPattern          = regexp.MustCompile(`19\d\d|20\d\d`)
+	separatedDatePattern = regexp.MustCompile(`^(\d{1,4})([\s/\\_.-])(\d{1,2})([\s/\\_.-])(\d{1,4})$`)
+)
+
+// dateMatches finds years and dates, with or without separators. Their
+// guesses grow with distance from the current year.
+func dateMatches(runes []rune) []match {
+	s := string(runes)
+	if len(s) != len(runes) {
+		// dates are ASCII, and byte offsets have to line up with runes
+		return nil
+	}
+	var matches []match
+	for _, loc := range yearPattern.FindAllStringIndex(s, -1) {
+		year, _ := strconv.Atoi(s[loc[0]:loc[1]])
+		matches = append(matches, match{i: loc[0], j: loc[1] - 1, guesses: yearSpace(year), pattern: patternDate})
+	}
+	for i := 0; i < len(s); i++ {
+		for j := i + 3; j < len(s) && j-i < 10; j++ {
+			candidate := s[i : j+1]
+			if year, ok := digitDate(candidate); ok {
+				matches = append(matches, match{i: i, j: j, guesses: 365 * yearSpace(year), pattern: patternDate})
+				continue
+			}
+			if parts := separatedDatePattern.FindStringSubmatch(candidate); parts != nil && parts[2] == parts[4] {
+				if year, ok := validDate(parts[1], parts[3], parts[5]); ok {
+					matches = append(matches, match{i: i, j: j, guesses: 365 * yearSpace(year) * 4, pattern: patternDate})
+				}
+			}
+		}
+	}
+	return matches
+}
+
+func yearSpace(year int) float64 {
+	return math.Max(math.Abs(float64(year-time.Now().Year())), minYearSpace)
+}
+
+// digitDate reports whether a run of 4 to 8 digits reads as a day, month
+// and year in some order.
+func digitDate(s string) (int, bool) {
+	if len(s) < 4 || len(s) > 8 {
+		return 0, false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return 0, false
+		}
+	}
+	for split1 := 1; split1 < len(s)-1; split1++ {
+		for split2 := split1 + 1; split2 < len(s); split2++ {
+			if year, ok := validDate(s[:split1], s[split1:split2], s[split2:]); ok {
+				return year, true
+			}
+		}
+	}
+	return 0, false
+}
+
+func validDate(a, b, c string) (int, bool) {
+	orders := [][3]string{{a, b, c}, {b, a, c}, {c, b, a}, {c, a, b}}
+	for _, order := range orders {
+		day, month, year := atoi(order[0]), atoi(order[1]), order[2]
+		if len(year) != 2 && len(year) != 4 || len(order[0]) > 2 || len(order[1]) > 2 {
+			continue
+		}
+		y := atoi(year)
+		if len(year) == 2 {
+			if y > 50 {
+				y += 1900
+			} else {
+				y += 2000
+			}
+		}
+		if day >= 1 && day <= 31 && month >= 1 && month <= 12 && y >= 1000 && y <= 2050 {
+			return y, true
+		}
+	}
+	return 0, false
+}
+
+func atoi(s string) int {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return -1
+	}
+	return n
+}
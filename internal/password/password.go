@@ -0,0 +1,110 @@
+// Package password decides whether a new password is acceptable.
+package password
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	DefaultMinLength = 10
+	// DefaultMinScore asks for around 10^8 guesses, which is safe against
+	// online attacks and slows down offline ones against bcrypt hashes.
+	DefaultMinScore = 3
+)
+
+type Policy struct {
+	MinLength int
+	MinScore  int
+	// Breaches, when set, rejects passwords seen in known breaches.
+	Breaches BreachChecker
+}
+
+func DefaultPolicy() *Policy {
+	return &Policy{MinLength: DefaultMinLength, MinScore: DefaultMinScore}
+}
+
+// Error lists every way a password fails the policy, worded for the person
+// choosing it.
+type Error struct {
+	Reasons []string
+}
+
+func (e *Error) Error() string {
+	return "password rejected: " + strings.Join(e.Reasons, "; ")
+}
+
+func (e *Error) Messages() []string {
+	return e.Reasons
+}
+
+// Check returns an *Error if password breaks the policy. personal holds
+// things about the user that the password must not contain, like their name
+// and email address. Other errors mean the breach corpus couldn't be read.
+func (p *Policy) Check(ctx context.Context, password string, personal ...string) error {
+	var reasons []string
+	if utf8.RuneCountInString(password) < p.MinLength {
+		reasons = append(reasons, fmt.Sprintf("Password must be at least %d characters", p.MinLength))
+	}
+	if containsPersonal(password, personal) {
+		reasons = append(reasons, "Password must not contain your name, username or email address")
+	}
+	if estimate := Strength(password); estimate.Score < p.MinScore {
+		reasons = append(reasons, "Password is too easy to guess: "+advice(estimate))
+	}
+	if p.Breaches != nil {
+		count, err := p.Breaches.Breached(ctx, password)
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			reasons = append(reasons, "Password has appeared in a data breach and must not be used")
+		}
+	}
+	if len(reasons) > 0 {
+		return &Error{Reasons: reasons}
+	}
+	return nil
+}
+
+// containsPersonal looks for each piece of personal information, and for
+// the parts of an email address before the @, ignoring case. Parts shorter
+// than three characters match too much to be useful.
+func containsPersonal(password string, personal []string) bool {
+	lower := strings.ToLower(password)
+	for _, value := range personal {
+		value = strings.ToLower(strings.TrimSpace(value))
+		parts := []string{value}
+		if local, _, ok := strings.Cut(value, "@"); ok {
+			parts = append(parts, local)
+			parts = append(parts, strings.FieldsFunc(local, func(r rune) bool {
+				return r == '.' || r == '_' || r == '-' || r == '+'
+			})...)
+		}
+		for _, part := range parts {
+			if utf8.RuneCountInString(part) >= 3 && strings.Contains(lower, part) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func advice(estimate Estimate) string {
+	switch estimate.Pattern {
+	case patternDictionary:
+		return "avoid common words and passwords, or add more words"
+	case patternSpatial:
+		return "avoid runs of keys like qwerty"
+	case patternRepeat:
+		return "avoid repeated characters and words"
+	case patternSequence:
+		return "avoid sequences like abc or 123"
+	case patternDate:
+		return "avoid dates and years"
+	default:
+		return "make it longer"
+	}
+}
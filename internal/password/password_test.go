@@ -0,0 +1,79 @@
+package password
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type fakeBreaches map[string]int
+
+func (f fakeBreaches) Breached(ctx context.Context, password string) (int, error) {
+	return f[password], nil
+}
+
+type failingBreaches struct{}
+
+func (failingBreaches) Breached(ctx context.Context, password string) (int, error) {
+	return 0, errors.New("corpus unreadable")
+}
+
+func TestPolicyCheck(t *testing.T) {
+	const (
+		tooShort = "Password must be at least 10 characters"
+		personal = "Password must not contain your name, username or email address"
+		breached = "Password has appeared in a data breach and must not be used"
+		common   = "Password is too easy to guess: avoid common words and passwords, or add more words"
+		short    = "Password is too easy to guess: make it longer"
+	)
+	policy := &Policy{
+		MinLength: DefaultMinLength,
+		MinScore:  DefaultMinScore,
+		Breaches:  fakeBreaches{"kz7#qv2!mwLp": 3},
+	}
+	tests := []struct {
+		name        string
+		password    string
+		personal    []string
+		wantReasons []string
+	}{
+		{"strong", "x7#Kq9!mZ2@w", nil, nil},
+		{"too short", "x7#Kq9!m", nil, []string{tooShort, short}},
+		{"first name", "x7#Kq9!mAdaeze", []string{"Adaeze", "Al"}, []string{personal}},
+		{"short names are ignored", "x7#Kq9!mZ2al", []string{"Al"}, nil},
+		{"email local part", "x7#Kq9!m.jdoe.w", []string{"jdoe@example.com"}, []string{personal}},
+		{"email dotted part", "x7#Kq9!mZ2doe", []string{"jane.doe@example.com"}, []string{personal}},
+		{"breached", "kz7#qv2!mwLp", nil, []string{breached}},
+		{"too easy", "Password123", nil, []string{common}},
+		{"l33t", "Tr0ub4dor&3", nil, []string{common}},
+		{"everything", "ada", []string{"ada"}, []string{tooShort, personal, short}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.Check(context.Background(), tt.password, tt.personal...)
+			if tt.wantReasons == nil {
+				if err != nil {
+					t.Fatalf("got %v, want no error", err)
+				}
+				return
+			}
+			var policyErr *Error
+			if !errors.As(err, &policyErr) {
+				t.Fatalf("got %v, want *Error", err)
+			}
+			if !reflect.DeepEqual(policyErr.Messages(), tt.wantReasons) {
+				t.Errorf("got reasons %q, want %q", policyErr.Messages(), tt.wantReasons)
+			}
+		})
+	}
+}
+
+func TestPolicyCheckBreachError(t *testing.T) {
+	policy := &Policy{MinLength: DefaultMinLength, MinScore: DefaultMinScore, Breaches: failingBreaches{}}
+	err := policy.Check(context.Background(), "x7#Kq9!mZ2@w")
+	var policyErr *Error
+	if err == nil || errors.As(err, &policyErr) {
+		t.Errorf("got %v, want the corpus error", err)
+	}
+}
@@ -0,0 +1,159 @@
+package password
+
+import "math"
+
+// Estimate is how many guesses an attacker who knows the usual patterns
+// would need, and the 0-4 score that bins it the way zxcvbn does.
+type Estimate struct {
+	Guesses float64
+	Score   int
+	// Pattern is the weakest kind of pattern the password was found to be
+	// built from, or "" when it is all brute force.
+	Pattern string
+}
+
+const (
+	patternBruteforce = "bruteforce"
+	patternDictionary = "dictionary"
+	patternSpatial    = "spatial"
+	patternRepeat     = "repeat"
+	patternSequence   = "sequence"
+	patternDate       = "date"
+
+	bruteforceCardinality = 10
+	// minGuessesBeforeGrowingSequence penalises passwords built from many
+	// patterns, as guessing them means also guessing how they were joined.
+	minGuessesBeforeGrowingSequence = 10000
+)
+
+type match struct {
+	i, j    int
+	guesses float64
+	pattern string
+}
+
+// Strength estimates how hard s is to guess. It finds every dictionary word,
+// keyboard run, repeat, sequence and date in s and picks the cheapest way to
+// cover s with them, filling the gaps with brute force.
+func Strength(s string) Estimate {
+	runes := []rune(s)
+	if len(runes) == 0 {
+		return Estimate{Guesses: 1}
+	}
+	guesses, matches := mostGuessableSequence(runes, omnimatch(runes))
+	estimate := Estimate{Guesses: guesses, Score: score(guesses)}
+	longest := 0
+	for _, m := range matches {
+		if m.pattern != patternBruteforce && m.j-m.i+1 > longest {
+			longest = m.j - m.i + 1
+			estimate.Pattern = m.pattern
+		}
+	}
+	return estimate
+}
+
+func score(guesses float64) int {
+	const delta = 5
+	switch {
+	case guesses < 1e3+delta:
+		return 0
+	case guesses < 1e6+delta:
+		return 1
+	case guesses < 1e8+delta:
+		return 2
+	case guesses < 1e10+delta:
+		return 3
+	default:
+		return 4
+	}
+}
+
+func omnimatch(runes []rune) []match {
+	var matches []match
+	matches = append(matches, dictionaryMatches(runes)...)
+	matches = append(matches, spatialMatches(runes)...)
+	matches = append(matches, repeatMatches(runes)...)
+	matches = append(matches, sequenceMatches(runes)...)
+	matches = append(matches, dateMatches(runes)...)
+	return matches
+}
+
+type step struct {
+	guesses float64
+	match   match
+	prev    int
+}
+
+// mostGuessableSequence finds the cover of runes with the fewest guesses.
+// best[k][l] is the cheapest product of l matches that ends at k.
+func mostGuessableSequence(runes []rune, matches []match) (float64, []match) {
+	n := len(runes)
+	byEnd := make([][]match, n)
+	for _, m := range matches {
+		m.guesses = math.Max(m.guesses, minGuesses(m))
+		byEnd[m.j] = append(byEnd[m.j], m)
+	}
+	for j := 0; j < n; j++ {
+		for i := 0; i <= j; i++ {
+			byEnd[j] = append(byEnd[j], match{i: i, j: j, guesses: math.Pow(bruteforceCardinality, float64(j-i+1)), pattern: patternBruteforce})
+		}
+	}
+
+	best := make([]map[int]step, n)
+	for k := range best {
+		best[k] = make(map[int]step)
+	}
+	for k := 0; k < n; k++ {
+		for _, m := range byEnd[k] {
+			if m.i == 0 {
+				consider(best[k], 1, step{guesses: m.guesses, match: m, prev: 0})
+				continue
+			}
+			for l, prev := range best[m.i-1] {
+				// two brute force runs side by side are one longer run
+				if m.pattern == patternBruteforce && prev.match.pattern == patternBruteforce {
+					continue
+				}
+				consider(best[k], l+1, step{guesses: prev.guesses * m.guesses, match: m, prev: l})
+			}
+		}
+	}
+
+	total, length := math.Inf(1), 0
+	for l, s := range best[n-1] {
+		g := factorial(l)*s.guesses + math.Pow(minGuessesBeforeGrowingSequence, float64(l-1))
+		if g < total {
+			total, length = g, l
+		}
+	}
+	sequence := make([]match, length)
+	for k, l := n-1, length; l > 0; l-- {
+		s := best[k][l]
+		sequence[l-1] = s.match
+		k = s.match.i - 1
+	}
+	return total, sequence
+}
+
+func consider(candidates map[int]step, l int, s step) {
+	if existing, ok := candidates[l]; !ok || s.guesses < existing.guesses {
+		candidates[l] = s
+	}
+}
+
+// minGuesses stops short patterns from looking cheaper than guessing the
+// characters one by one.
+func minGuesses(m match) float64 {
+	if m.j == m.i {
+		return bruteforceCardinality + 1
+	}
+	return 50
+}
+
+func factorial(n int) float64 {
+	f := 1.0
+	for i := 2; i <= n; i++ {
+		f *= float64(i)
+	}
+	return f
+}
@@ -0,0 +1,65 @@
+package password
+
+import "testing"
+
+func TestStrength(t *testing.T) {
+	tests := []struct {
+		password    string
+		wantScore   int
+		wantPattern string
+	}{
+		{"", 0, ""},
+		{"password", 0, patternDictionary},
+		{"P@ssw0rd", 0, patternDictionary},
+		{"drowssap", 0, patternDictionary},
+		{"Tr0ub4dor&3", 1, patternDictionary},
+		{"sdfghjkl;", 1, patternSpatial},
+		{"1qaz2wsx3edc", 1, patternSpatial},
+		{"aaaaaaaaaaaa", 0, patternRepeat},
+		{"abcabcabcabc", 0, patternRepeat},
+		{"abcdefghijkl", 0, patternSequence},
+		{"9876543210", 0, patternSequence},
+		{"1987", 0, patternDate},
+		{"13/05/1987", 1, patternDate},
+		{"correcthorsebatterystaple", 4, patternDictionary},
+		{"x7#Kq9!mZ2@w", 4, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.password, func(t *testing.T) {
+			got := Strength(tt.password)
+			if got.Score != tt.wantScore {
+				t.Errorf("got score %d (%g guesses), want %d", got.Score, got.Guesses, tt.wantScore)
+			}
+			if got.Pattern != tt.wantPattern {
+				t.Errorf("got pattern %q, want %q", got.Pattern, tt.wantPattern)
+			}
+		})
+	}
+}
+
+func TestStrengthGrowsWithLength(t *testing.T) {
+	short := Strength("kz7#q").Guesses
+	long := Strength("kz7#qv2!mw").Guesses
+	if long <= short {
+		t.Errorf("10 random characters (%g guesses) weren't stronger than 5 (%g)", long, short)
+	}
+}
+
+func TestUppercaseVariations(t *testing.T) {
+	tests := []struct {
+		word string
+		want float64
+	}{
+		{"password", 1},
+		{"Password", 2},
+		{"passworD", 2},
+		{"PASSWORD", 2},
+		{"pAssword", 8},
+		{"PassWord", 8 + 28},
+	}
+	for _, tt := range tests {
+		if got := uppercaseVariations([]rune(tt.word)); got != tt.want {
+			t.Errorf("uppercaseVariations(%q) = %g, want %g", tt.word, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreTake(t *testing.T) {
+	// three requests at once, then one every ten seconds
+	limit := Limit{PerMinute: 6, Burst: 3}
+	tests := []struct {
+		name    string
+		advance time.Duration
+		want    Result
+	}{
+		{"first request", 0, Result{Allowed: true, Limit: 3, Remaining: 2, ResetAfter: 10 * time.Second}},
+		{"second request", 0, Result{Allowed: true, Limit: 3, Remaining: 1, ResetAfter: 20 * time.Second}},
+		{"empties the bucket", 0, Result{Allowed: true, Limit: 3, Remaining: 0, ResetAfter: 30 * time.Second}},
+		{"denied when empty", 0, Result{Limit: 3, RetryAfter: 10 * time.Second, ResetAfter: 30 * time.Second}},
+		{"half a token refilled", 5 * time.Second, Result{Limit: 3, RetryAfter: 5 * time.Second, ResetAfter: 25 * time.Second}},
+		{"a whole token refilled", 5 * time.Second, Result{Allowed: true, Limit: 3, Remaining: 0, ResetAfter: 30 * time.Second}},
+		{"refill stops at the burst", time.Hour, Result{Allowed: true, Limit: 3, Remaining: 2, ResetAfter: 10 * time.Second}},
+	}
+
+	now := time.Date(2024, 11, 1, 12, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	for _, tt := range tests {
+		now = now.Add(tt.advance)
+		got, err := store.Take(context.Background(), "key", limit)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMemoryStoreKeysAreIndependent(t *testing.T) {
+	now := time.Date(2024, 11, 1, 12, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	limit := PerMinute(1)
+
+	ctx := context.Background()
+	if result, _ := store.Take(ctx, "a", limit); !result.Allowed {
+		t.Fatal("first request for a was denied")
+	}
+	if result, _ := store.Take(ctx, "a", limit); result.Allowed {
+		t.Fatal("second request for a was allowed")
+	}
+	if result, _ := store.Take(ctx, "b", limit); !result.Allowed {
+		t.Error("b was limited by a's requests")
+	}
+}
+
+func TestPerMinute(t *testing.T) {
+	tests := []struct {
+		n    int
+		want Limit
+	}{
+		{60, Limit{PerMinute: 60, Burst: 60}},
+		{1, Limit{PerMinute: 1, Burst: 1}},
+		{0, Limit{PerMinute: 1, Burst: 1}},
+		{-5, Limit{PerMinute: 1, Burst: 1}},
+	}
+	for _, tt := range tests {
+		if got := PerMinute(tt.n); got != tt.want {
+			t.Errorf("PerMinute(%d) = %+v, want %+v", tt.n, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+// Package ratelimit throttles requests with token buckets.
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Limit is a bucket that holds Burst tokens and refills at PerMinute tokens
+// a minute. Each request takes one token.
+type Limit struct {
+	PerMinute int
+	Burst     int
+}
+
+// PerMinute is a limit that allows n requests a minute, all at once if the
+// client likes. Anything below one request a minute is raised to one.
+func PerMinute(n int) Limit {
+	if n < 1 {
+		n = 1
+	}
+	return Limit{PerMinute: n, Burst: n}
+}
+
+func (l Limit) interval() time.Duration {
+	return time.Minute / time.Duration(l.PerMinute)
+}
+
+// Result describes the bucket after a request took from it.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// RetryAfter is how long until the next token, when Allowed is false.
+	RetryAfter time.Duration
+	// ResetAfter is how long until the bucket is full again.
+	ResetAfter time.Duration
+}
+
+// Store keeps buckets. The in-memory store limits each API instance on its
+// own; replicas that should share a budget need a shared implementation.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
@@ -0,0 +1,115 @@
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"github.com/rx-rz/65ch/internal/data"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	activationTokenTTL = 72 * time.Hour
+	// activationResendInterval is how long a user has to wait before asking
+	// for another activation email.
+	activationResendInterval = 2 * time.Minute
+)
+
+func (api *API) activationURL(token string) string {
+	return fmt.Sprintf("%s/activate?token=%s", api.env.ClientUrl, url.QueryEscape(token))
+}
+
+type ActivateUserRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+func (api *API) activateUserHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := api.CreateContext()
+	defer cancel()
+
+	var req ActivateUserRequest
+	err := api.readJSON(w, r, &req)
+	if err != nil {
+		api.badRequestResponse(w, err, err.Error())
+		return
+	}
+
+	v := validator.New()
+	if validationError := v.Struct(req); validationError != nil {
+		api.failedValidationResponse(w, validationError)
+		return
+	}
+	updateInfo, err := api.models.ActivationTokens.Activate(ctx, req.Token)
+	if errors.Is(err, data.ErrRecordNotFound) {
+		api.writeErrorResponse(w, http.StatusUnprocessableEntity, ErrExpired, "Activation token is invalid or has expired", err)
+		return
+	}
+	if err != nil {
+		api.handleDBError(w, r, err)
+		return
+	}
+	api.writeSuccessResponse(w, http.StatusOK, envelope{"data": updateInfo}, "Account activated successfully")
+}
+
+type ResendActivationRequest struct {
+	Email string `json:"email" validate:"required,email,max=255"`
+}
+
+func (api *API) resendActivationHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := api.CreateContext()
+	defer cancel()
+
+	var req ResendActivationRequest
+	err := api.readJSON(w, r, &req)
+	if err != nil {
+		api.badRequestResponse(w, err, err.Error())
+		return
+	}
+
+	v := validator.New()
+	if validationError := v.Struct(req); validationError != nil {
+		api.failedValidationResponse(w, validationError)
+		return
+	}
+
+	const message = "An activation email has been sent if the account exists and is not yet active"
+	user, err := api.models.Users.GetByEmail(ctx, req.Email)
+	if errors.Is(err, data.ErrRecordNotFound) {
+		api.writeSuccessResponse(w, http.StatusOK, nil, message)
+		return
+	}
+	if err != nil {
+		api.handleDBError(w, r, err)
+		return
+	}
+	if user.Activated {
+		api.writeSuccessResponse(w, http.StatusOK, nil, message)
+		return
+	}
+
+	latest, err := api.models.ActivationTokens.GetLatestForUser(ctx, user.ID)
+	if err != nil && !errors.Is(err, data.ErrRecordNotFound) {
+		api.handleDBError(w, r, err)
+		return
+	}
+	if latest != nil {
+		if wait := activationResendInterval - time.Since(latest.CreatedAt); wait > 0 {
+			api.rateLimitExceededResponse(w, wait, "Please wait before requesting another activation email")
+			return
+		}
+	}
+
+	activationToken, err := api.models.ActivationTokens.Create(ctx, user.ID, activationTokenTTL)
+	if err != nil {
+		api.handleDBError(w, r, err)
+		return
+	}
+	api.sendEmail(user.Email, "activation.tmpl", map[string]any{
+		"firstName":     user.FirstName,
+		"activationURL": api.activationURL(activationToken.Token),
+		"expiresIn":     activationTokenTTL.String(),
+	})
+	api.writeSuccessResponse(w, http.StatusOK, nil, message)
+}
@@ -0,0 +1,90 @@
+package rest
+
+import (
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"github.com/rx-rz/65ch/internal/data"
+	"net/http"
+)
+
+func (api *API) initializeAdminRoutes() {
+	adminsOnly := api.requireRole(data.RoleAdmin)
+	api.router.HandlerFunc(http.MethodPatch, "/v1/admin/users/:id/role", adminsOnly(api.updateUserRoleHandler))
+	api.router.HandlerFunc(http.MethodGet, "/v1/admin/users/:id/role-changes", adminsOnly(api.listRoleChangesHandler))
+}
+
+type UpdateUserRoleRequest struct {
+	Role   string `json:"role" validate:"required,oneof=reader author editor admin"`
+	Reason string `json:"reason" validate:"max=500"`
+}
+
+func (api *API) updateUserRoleHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := api.CreateContext()
+	defer cancel()
+
+	id, err := api.readParam(r, "id")
+	if err != nil {
+		api.badRequestResponse(w, err, "ID parameter not provided")
+		return
+	}
+	var req UpdateUserRoleRequest
+	err = api.readJSON(w, r, &req)
+	if err != nil {
+		api.badRequestResponse(w, err, err.Error())
+		return
+	}
+
+	v := validator.New()
+	if validationError := v.Struct(req); validationError != nil {
+		api.failedValidationResponse(w, validationError)
+		return
+	}
+	admin := api.contextGetUser(r)
+	// an admin demoting themselves could leave nobody able to undo it
+	if id == admin.ID {
+		api.forbiddenResponse(w, "You cannot change your own role")
+		return
+	}
+	change, err := api.models.Users.UpdateRole(ctx, &data.RoleChange{
+		UserID:    id,
+		ChangedBy: &admin.ID,
+		NewRole:   req.Role,
+		Reason:    req.Reason,
+	})
+	if err != nil {
+		api.handleDBError(w, r, err)
+		return
+	}
+	api.logger.PrintInfo("user role changed", map[string]string{
+		"user_id":    change.UserID,
+		"changed_by": admin.ID,
+		"old_role":   change.OldRole,
+		"new_role":   change.NewRole,
+	})
+	api.writeSuccessResponse(w, http.StatusOK, envelope{"role_change": change}, "User role updated successfully")
+}
+
+func (api *API) listRoleChangesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := api.CreateContext()
+	defer cancel()
+
+	id, err := api.readParam(r, "id")
+	if err != nil {
+		api.badRequestResponse(w, err, "ID parameter not provided")
+		return
+	}
+	if _, err = api.models.Users.GetByID(ctx, id); err != nil {
+		if errors.Is(err, data.ErrRecordNotFound) {
+			api.notFoundResponse(w, "User not found")
+			return
+		}
+		api.handleDBError(w, r, err)
+		return
+	}
+	changes, err := api.models.Users.GetRoleChanges(ctx, id)
+	if err != nil {
+		api.handleDBError(w, r, err)
+		return
+	}
+	api.writeSuccessResponse(w, http.StatusOK, envelope{"role_changes": changes}, "")
+}
@@ -3,34 +3,90 @@ package rest
 import (
 	"context"
 	"github.com/julienschmidt/httprouter"
+	"github.com/rx-rz/65ch/internal/auth"
 	"github.com/rx-rz/65ch/internal/config"
 	"github.com/rx-rz/65ch/internal/data"
 	"github.com/rx-rz/65ch/internal/jsonlog"
+	"github.com/rx-rz/65ch/internal/lockout"
+	"github.com/rx-rz/65ch/internal/mailer"
+	"github.com/rx-rz/65ch/internal/password"
+	"github.com/rx-rz/65ch/internal/ratelimit"
+	"github.com/rx-rz/65ch/internal/views"
 	"net/http"
 	"time"
 )
 
 type API struct {
-	router  *httprouter.Router
-	models  data.Models
-	logger  *jsonlog.Logger
-	context context.Context
+	router     *httprouter.Router
+	models     data.Models
+	logger     *jsonlog.Logger
+	mailer     mailer.Mailer
+	keyring    *auth.Keyring
+	totp       *auth.TOTP
+	guards     guards
+	rateLimits rateLimits
+	passwords  *password.Policy
+	views      *views.Recorder
+	env        config.Env
+	context    context.Context
 }
 
 func InitializeAPI(cfg *config.Config) *http.Server {
+	if cfg.Views == nil {
+		panic("rest: config.Views is required")
+	}
+	attemptStore := cfg.AttemptStore
+	if attemptStore == nil {
+		attemptStore = lockout.NewMemoryStore(24 * time.Hour)
+	}
+	passwordPolicy := cfg.PasswordPolicy
+	if passwordPolicy == nil {
+		passwordPolicy = password.DefaultPolicy()
+	}
+	rateLimitStore := cfg.RateLimitStore
+	if rateLimitStore == nil {
+		rateLimitStore = ratelimit.NewMemoryStore()
+	}
 	api := &API{
-		router: httprouter.New(),
-		models: data.NewModels(cfg.DB),
-		logger: cfg.Logger,
+		router:  httprouter.New(),
+		models:  data.NewModels(cfg.DB),
+		logger:  cfg.Logger,
+		mailer:  cfg.Mailer,
+		keyring: cfg.Keyring,
+		totp:    auth.NewTOTP("65ch"),
+		guards:  newGuards(attemptStore),
+		rateLimits: rateLimits{
+			store: rateLimitStore,
+			auth:  ratelimit.PerMinute(cfg.Env.RateLimitAuth),
+			write: ratelimit.PerMinute(cfg.Env.RateLimitWrite),
+			read:  ratelimit.PerMinute(cfg.Env.RateLimitRead),
+		},
+		passwords: passwordPolicy,
+		views:     cfg.Views,
+		env:       cfg.Env,
 	}
 
 	api.initializeUserRoutes()
+	api.initializeSessionRoutes()
+	api.initializeMFARoutes()
+	api.initializeKeyRoutes()
 	api.initializeCategoryRoutes()
 	api.initializeTagRoutes()
 	api.initializeArticleRoutes()
+	api.initializeArticleRevisionRoutes()
+	api.initializeArticleEngagementRoutes()
+	api.initializeCommentRoutes()
+	api.initializeTopicFollowRoutes()
+	api.initializeFeedRoutes()
+	api.initializeNotificationRoutes()
+	api.initializeAdminRoutes()
 
+	var handler http.Handler = api.router
+	if cfg.Env.RateLimitEnabled {
+		handler = api.rateLimit(handler)
+	}
 	return &http.Server{
-		Handler:      api.router,
+		Handler:      handler,
 		Addr:         ":8080",
 		IdleTimeout:  time.Minute,
 		ReadTimeout:  time.Second * 10,
@@ -1,62 +1,64 @@
 package rest
 
 import (
+	"context"
+	"errors"
 	"github.com/go-playground/validator/v10"
 	"github.com/rx-rz/65ch/internal/data"
+	"github.com/rx-rz/65ch/internal/utils"
 	"net/http"
 )
 
-type CommentOnArticleRequest struct {
-	UserID    string `json:"user_id" validate:"required"`
-	ArticleID string `json:"article_id" validate:"required"`
-	Content   string `json:"content" validate:"required"`
+func (api *API) initializeArticleEngagementRoutes() {
+	api.router.HandlerFunc(http.MethodPost, "/v1/articles/:id/like", api.authorizedAccessOnly(api.likeArticleHandler))
+	api.router.HandlerFunc(http.MethodDelete, "/v1/articles/:id/like", api.authorizedAccessOnly(api.unlikeArticleHandler))
+	api.router.HandlerFunc(http.MethodPost, "/v1/articles/:id/save", api.authorizedAccessOnly(api.saveArticleHandler))
+	api.router.HandlerFunc(http.MethodDelete, "/v1/articles/:id/save", api.authorizedAccessOnly(api.unsaveArticleHandler))
+	api.router.HandlerFunc(http.MethodPost, "/v1/articles/:id/views", api.optionalAccess(api.increaseArticleViewsHandler))
+	api.router.HandlerFunc(http.MethodPost, "/v1/articles/:id/reads", api.optionalAccess(api.recordReadProgressHandler))
+	api.router.HandlerFunc(http.MethodGet, "/v1/articles/:id/stats", api.authorizedAccessOnly(api.viewArticleStatisticsHandler))
 }
 
-func (api *API) commentOnArticleHandler(w http.ResponseWriter, r *http.Request) {
-	ctx, cancel := api.CreateContext()
-	defer cancel()
-
-	var req CommentOnArticleRequest
-	err := api.readJSON(w, r, &req)
+// readPublishedArticle loads the article named by the id path parameter,
+// treating anything not yet published as missing.
+func (api *API) readPublishedArticle(ctx context.Context, w http.ResponseWriter, r *http.Request) (*data.Article, bool) {
+	id, err := api.readParam(r, "id")
 	if err != nil {
-		api.badRequestResponse(w, err, err.Error())
-		return
+		api.badRequestResponse(w, err, "ID parameter not provided")
+		return nil, false
 	}
-
-	v := validator.New()
-	if validationError := v.Struct(req); validationError != nil {
-		api.failedValidationResponse(w, validationError)
-		return
+	article, err := api.models.Articles.GetByID(ctx, id)
+	if err != nil {
+		api.handleDBError(w, r, err)
+		return nil, false
 	}
-
-	_, err = api.models.Comments.Create(ctx, &data.Comment{UserID: req.UserID, ArticleID: req.ArticleID, Content: req.Content})
-}
-
-type EngageArticleRequest struct {
-	UserID    string `json:"user_id" validate:"required"`
-	ArticleID string `json:"article_id" validate:"required"`
+	if article.Status != "published" {
+		api.notFoundResponse(w, "Article not found")
+		return nil, false
+	}
+	return article, true
 }
 
 func (api *API) likeArticleHandler(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := api.CreateContext()
 	defer cancel()
 
-	var req EngageArticleRequest
-	err := api.readJSON(w, r, &req)
-	if err != nil {
-		api.badRequestResponse(w, err, err.Error())
-		return
-	}
-	v := validator.New()
-	if validationError := v.Struct(req); validationError != nil {
-		api.failedValidationResponse(w, validationError)
+	article, ok := api.readPublishedArticle(ctx, w, r)
+	if !ok {
 		return
 	}
-	info, err := api.models.Articles.Like(ctx, req.UserID, req.ArticleID)
+	user := api.contextGetUser(r)
+	info, err := api.models.Articles.Like(ctx, user.ID, article.ID)
 	if err != nil {
 		api.handleDBError(w, r, err)
 		return
 	}
+	api.createNotification(ctx, &data.Notification{
+		RecipientID: article.AuthorID,
+		ActorID:     user.ID,
+		Type:        data.NotificationLike,
+		ArticleID:   &article.ID,
+	})
 	api.writeSuccessResponse(w, http.StatusOK, envelope{"data": info}, "Article liked successfully")
 
 }
@@ -65,18 +67,12 @@ func (api *API) unlikeArticleHandler(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := api.CreateContext()
 	defer cancel()
 
-	var req EngageArticleRequest
-	err := api.readJSON(w, r, &req)
+	id, err := api.readParam(r, "id")
 	if err != nil {
-		api.badRequestResponse(w, err, err.Error())
-		return
-	}
-	v := validator.New()
-	if validationError := v.Struct(req); validationError != nil {
-		api.failedValidationResponse(w, validationError)
+		api.badRequestResponse(w, err, "ID parameter not provided")
 		return
 	}
-	info, err := api.models.Articles.Unlike(ctx, req.UserID, req.ArticleID)
+	info, err := api.models.Articles.Unlike(ctx, api.contextGetUser(r).ID, id)
 	if err != nil {
 		api.handleDBError(w, r, err)
 		return
@@ -89,52 +85,140 @@ func (api *API) saveArticleHandler(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := api.CreateContext()
 	defer cancel()
 
-	var req EngageArticleRequest
-	err := api.readJSON(w, r, &req)
-	if err != nil {
-		api.badRequestResponse(w, err, err.Error())
+	article, ok := api.readPublishedArticle(ctx, w, r)
+	if !ok {
 		return
 	}
-	v := validator.New()
-	if validationError := v.Struct(req); validationError != nil {
-		api.failedValidationResponse(w, validationError)
+	info, err := api.models.Articles.Save(ctx, api.contextGetUser(r).ID, article.ID)
+	if err != nil {
+		api.handleDBError(w, r, err)
 		return
 	}
+	api.writeSuccessResponse(w, http.StatusOK, envelope{"data": info}, "Article saved successfully")
+
+}
 
-	info, err := api.models.Articles.Save(ctx, req.UserID, req.ArticleID)
+func (api *API) unsaveArticleHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := api.CreateContext()
+	defer cancel()
+
+	id, err := api.readParam(r, "id")
+	if err != nil {
+		api.badRequestResponse(w, err, "ID parameter not provided")
+		return
+	}
+	info, err := api.models.Articles.Unsave(ctx, api.contextGetUser(r).ID, id)
 	if err != nil {
 		api.handleDBError(w, r, err)
 		return
 	}
-	api.writeSuccessResponse(w, http.StatusOK, envelope{"data": info}, "Article saved successfully")
+	api.writeSuccessResponse(w, http.StatusOK, envelope{"data": info}, "Article unsaved successfully")
+}
 
+func (api *API) listArticleLikesHandler() {}
+
+// viewerKey identifies who is reading for view counting: the user when
+// signed in, otherwise a hash of their address and browser.
+func (api *API) viewerKey(r *http.Request) string {
+	if user, ok := api.contextLookupUser(r); ok {
+		return "user:" + user.ID
+	}
+	return "anon:" + utils.HashToken(clientIP(r)+"|"+r.UserAgent())
 }
 
-func (api *API) unsaveArticleHandler(w http.ResponseWriter, r *http.Request) {
+// increaseArticleViewsHandler counts a view when a reader opens an article.
+// Authors reading their own work and repeat visits within the dedupe window
+// aren't counted.
+func (api *API) increaseArticleViewsHandler(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := api.CreateContext()
 	defer cancel()
-	var req EngageArticleRequest
-	err := api.readJSON(w, r, &req)
+
+	article, ok := api.readPublishedArticle(ctx, w, r)
+	if !ok {
+		return
+	}
+	counted := false
+	if user, ok := api.contextLookupUser(r); !ok || user.ID != article.AuthorID {
+		counted = api.views.View(article.ID, api.viewerKey(r))
+	}
+	api.writeSuccessResponse(w, http.StatusAccepted, envelope{"counted": counted}, "")
+}
+
+type ReadProgressRequest struct {
+	ReadDepth int `json:"read_depth" validate:"min=0,max=100"`
+	Seconds   int `json:"seconds" validate:"min=0,max=3600"`
+}
+
+// recordReadProgressHandler takes the beacons a reader's browser sends while
+// they read: how far down they have scrolled, in percent, and the seconds
+// spent on the page since the last beacon. Clients should send them with
+// fetch's keepalive rather than sendBeacon, so the Authorization header
+// goes along and the progress lands on the signed in reader's view.
+func (api *API) recordReadProgressHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := api.readParam(r, "id")
 	if err != nil {
-		api.badRequestResponse(w, err, err.Error())
+		api.badRequestResponse(w, err, "ID parameter not provided")
 		return
 	}
 	v := validator.New()
+	// a malformed id would fail the whole batch it is flushed with
+	if v.Var(id, "uuid") != nil {
+		api.notFoundResponse(w, "Article not found")
+		return
+	}
+	var req ReadProgressRequest
+	err = api.readJSON(w, r, &req)
+	if err != nil {
+		api.badRequestResponse(w, err, err.Error())
+		return
+	}
 	if validationError := v.Struct(req); validationError != nil {
 		api.failedValidationResponse(w, validationError)
 		return
 	}
+	// beacons for articles that don't exist, or views that were never
+	// counted, match no view when flushed and are dropped there
+	api.views.Beacon(data.ReadBeacon{
+		ArticleID: id,
+		ViewerKey: api.viewerKey(r),
+		ReadDepth: req.ReadDepth,
+		Seconds:   req.Seconds,
+	})
+	api.writeSuccessResponse(w, http.StatusAccepted, nil, "")
+}
 
-	info, err := api.models.Articles.Unsave(ctx, req.UserID, req.ArticleID)
+// viewArticleStatisticsHandler shows an article's author how it is doing
+// over the last ?days= days, 30 by default.
+func (api *API) viewArticleStatisticsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := api.CreateContext()
+	defer cancel()
+
+	id, err := api.readParam(r, "id")
+	if err != nil {
+		api.badRequestResponse(w, err, "ID parameter not provided")
+		return
+	}
+	days, err := api.readInt(r.URL.Query(), "days", 30)
+	if err != nil {
+		api.badRequestResponse(w, err, err.Error())
+		return
+	}
+	if days < 1 || days > 365 {
+		api.badRequestResponse(w, errors.New("days out of range"), "days must be between 1 and 365")
+		return
+	}
+	article, err := api.models.Articles.GetByID(ctx, id)
 	if err != nil {
 		api.handleDBError(w, r, err)
 		return
 	}
-	api.writeSuccessResponse(w, http.StatusOK, envelope{"data": info}, "Article unsaved successfully")
+	if !api.isArticleAuthor(w, r, article) {
+		return
+	}
+	stats, err := api.models.Statistics.Get(ctx, article.ID, days)
+	if err != nil {
+		api.handleDBError(w, r, err)
+		return
+	}
+	api.writeSuccessResponse(w, http.StatusOK, envelope{"statistics": stats}, "")
 }
-
-func (api *API) listArticleLikesHandler() {}
-
-func (api *API) viewArticleStatisticsHandler() {}
-
-func (api *API) increaseArticleViewsHandler() {}
@@ -0,0 +1,180 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"github.com/rx-rz/65ch/internal/data"
+	"github.com/rx-rz/65ch/internal/utils"
+	"net/http"
+	"strconv"
+)
+
+func (api *API) initializeArticleRevisionRoutes() {
+	api.router.HandlerFunc(http.MethodGet, "/v1/articles/:id/revisions", api.authorizedAccessOnly(api.listArticleRevisionsHandler))
+	api.router.HandlerFunc(http.MethodGet, "/v1/articles/:id/revisions/:revision", api.authorizedAccessOnly(api.getArticleRevisionHandler))
+	api.router.HandlerFunc(http.MethodPost, "/v1/articles/:id/revisions/:revision/restore", api.authorsOnly(api.restoreArticleRevisionHandler))
+	api.router.HandlerFunc(http.MethodGet, "/v1/articles/:id/diff", api.authorizedAccessOnly(api.diffArticleRevisionsHandler))
+}
+
+// readOwnedArticle loads the article named by the id path parameter and makes
+// sure the authenticated user wrote it. It writes the error response itself
+// and reports false when the handler should stop.
+func (api *API) readOwnedArticle(ctx context.Context, w http.ResponseWriter, r *http.Request) (*data.Article, bool) {
+	id, err := api.readParam(r, "id")
+	if err != nil {
+		api.badRequestResponse(w, err, "ID parameter not provided")
+		return nil, false
+	}
+	article, err := api.models.Articles.GetByID(ctx, id)
+	if err != nil {
+		api.handleDBError(w, r, err)
+		return nil, false
+	}
+	if !api.isArticleAuthor(w, r, article) {
+		return nil, false
+	}
+	return article, true
+}
+
+// isArticleAuthor reports whether the authenticated user wrote article, and
+// answers 403 when they didn't.
+func (api *API) isArticleAuthor(w http.ResponseWriter, r *http.Request, article *data.Article) bool {
+	if article.AuthorID != api.contextGetUser(r).ID {
+		api.forbiddenResponse(w, "You are not the author of this article")
+		return false
+	}
+	return true
+}
+
+func (api *API) readRevisionParam(r *http.Request) (int, error) {
+	param, err := api.readParam(r, "revision")
+	if err != nil {
+		return 0, err
+	}
+	revision, err := strconv.Atoi(param)
+	if err != nil || revision < 1 {
+		return 0, errors.New("revision must be a positive integer")
+	}
+	return revision, nil
+}
+
+func (api *API) listArticleRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := api.CreateContext()
+	defer cancel()
+
+	article, ok := api.readOwnedArticle(ctx, w, r)
+	if !ok {
+		return
+	}
+	revisions, err := api.models.Revisions.GetAllForArticle(ctx, article.ID)
+	if err != nil {
+		api.handleDBError(w, r, err)
+		return
+	}
+	api.writeSuccessResponse(w, http.StatusOK, envelope{"revisions": revisions}, "")
+}
+
+func (api *API) getArticleRevisionHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := api.CreateContext()
+	defer cancel()
+
+	article, ok := api.readOwnedArticle(ctx, w, r)
+	if !ok {
+		return
+	}
+	revisionNumber, err := api.readRevisionParam(r)
+	if err != nil {
+		api.badRequestResponse(w, err, err.Error())
+		return
+	}
+	revision, err := api.models.Revisions.Get(ctx, article.ID, revisionNumber)
+	if err != nil {
+		api.handleDBError(w, r, err)
+		return
+	}
+	api.writeSuccessResponse(w, http.StatusOK, envelope{"revision": revision}, "")
+}
+
+func (api *API) diffArticleRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := api.CreateContext()
+	defer cancel()
+
+	article, ok := api.readOwnedArticle(ctx, w, r)
+	if !ok {
+		return
+	}
+	qs := r.URL.Query()
+	from, err := api.readInt(qs, "from", 0)
+	if err != nil || from < 1 {
+		api.badRequestResponse(w, err, "from must be a positive revision number")
+		return
+	}
+	// to defaults to 0, which compares against the article's current content
+	to, err := api.readInt(qs, "to", 0)
+	if err != nil || to < 0 {
+		api.badRequestResponse(w, err, "to must be a revision number")
+		return
+	}
+	fromRevision, err := api.models.Revisions.Get(ctx, article.ID, from)
+	if err != nil {
+		api.handleDBError(w, r, err)
+		return
+	}
+	toRevision := &data.ArticleRevision{Title: article.Title, Content: article.Content}
+	if to != 0 {
+		toRevision, err = api.models.Revisions.Get(ctx, article.ID, to)
+		if err != nil {
+			api.handleDBError(w, r, err)
+			return
+		}
+	}
+	diff, err := utils.DiffLines(fromRevision.Content, toRevision.Content)
+	if errors.Is(err, utils.ErrDiffTooLarge) {
+		api.unprocessableEntityResponse(w, "These revisions differ in too many lines to compare")
+		return
+	}
+	api.writeSuccessResponse(w, http.StatusOK, envelope{
+		"from":       from,
+		"to":         to,
+		"from_title": fromRevision.Title,
+		"to_title":   toRevision.Title,
+		"diff":       diff,
+	}, "")
+}
+
+func (api *API) restoreArticleRevisionHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := api.CreateContext()
+	defer cancel()
+
+	article, ok := api.readOwnedArticle(ctx, w, r)
+	if !ok {
+		return
+	}
+	revisionNumber, err := api.readRevisionParam(r)
+	if err != nil {
+		api.badRequestResponse(w, err, err.Error())
+		return
+	}
+	revision, err := api.models.Revisions.Get(ctx, article.ID, revisionNumber)
+	if err != nil {
+		api.handleDBError(w, r, err)
+		return
+	}
+	// restoring goes through Update, so the content being replaced is itself
+	// kept as a new revision
+	article.Title = revision.Title
+	article.Content = revision.Content
+	var ifMatch bool
+	article.Version, ifMatch, err = api.expectedVersion(r, nil, article.Version)
+	if err != nil {
+		api.badRequestResponse(w, err, err.Error())
+		return
+	}
+	updateInfo, err := api.models.Articles.Update(ctx, article)
+	if err != nil {
+		api.updateErrorResponse(w, r, err, ifMatch)
+		return
+	}
+	api.setETag(w, article.Version)
+	api.writeSuccessResponse(w, http.StatusOK, envelope{"data": updateInfo}, "Revision restored successfully")
+}
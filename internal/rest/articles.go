@@ -1,24 +1,40 @@
 package rest
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"github.com/go-playground/validator/v10"
 	"github.com/rx-rz/65ch/internal/data"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
 )
 
 func (api *API) initializeArticleRoutes() {
-	api.router.HandlerFunc(http.MethodPost, "/v1/articles", api.publishArticleHandler)
-	api.router.HandlerFunc(http.MethodPost, "/v1/articles/draft", api.createDraftHandler)
+	api.router.HandlerFunc(http.MethodGet, "/v1/articles", api.listArticlesHandler)
+	api.router.HandlerFunc(http.MethodPost, "/v1/articles", api.authorsOnly(api.requireActivation(api.publishArticleHandler)))
+	api.router.HandlerFunc(http.MethodGet, "/v1/search", api.searchArticlesHandler)
+	// also serves /v1/articles/trending, which httprouter can't register
+	// next to the :id wildcard
+	api.router.HandlerFunc(http.MethodGet, "/v1/articles/:id", api.optionalAccess(api.getArticleDetailsHandler))
+	api.router.HandlerFunc(http.MethodGet, "/v1/@:username/:slug", api.optionalAccess(api.getArticleBySlugHandler))
+	api.router.HandlerFunc(http.MethodPost, "/v1/drafts", api.authorsOnly(api.createDraftHandler))
+	// keeps the old POST /v1/articles/draft working, as httprouter can't
+	// register it next to the :id wildcard either
+	api.router.HandlerFunc(http.MethodPost, "/v1/articles/:id", api.legacyDraftHandler)
 }
 
 type CreateArticleRequest struct {
-	ID         *string `json:"id"`
-	AuthorID   string  `json:"author_id" validate:"required"`
-	Title      string  `json:"title" validate:"required"`
-	Content    string  `json:"content" validate:"required"`
-	TagIDs     []int   `json:"tag_ids"`
-	CategoryID int     `json:"category_id" validate:"required"`
+	ID         *string    `json:"id"`
+	Title      string     `json:"title" validate:"required"`
+	Content    string     `json:"content" validate:"required"`
+	TagIDs     []int      `json:"tag_ids"`
+	CategoryID int        `json:"category_id" validate:"required"`
+	PublishAt  *time.Time `json:"publish_at" validate:"omitempty,gt"`
+	Version    *int       `json:"version"`
 }
 
 func (api *API) publishArticleHandler(w http.ResponseWriter, r *http.Request) {
@@ -37,49 +53,78 @@ func (api *API) publishArticleHandler(w http.ResponseWriter, r *http.Request) {
 		api.failedValidationResponse(w, validationError)
 		return
 	}
-	_, err = api.models.Users.GetByID(ctx, req.AuthorID)
-	if err != nil {
-		api.handleDBError(w, r, err)
-		return
-	}
 	_, err = api.models.Categories.GetByID(strconv.Itoa(req.CategoryID))
 	if err != nil {
 		api.handleDBError(w, r, err)
 		return
 	}
+
+	// a future publish_at schedules the article for the publisher worker
+	status, message := "published", "Article successfully published"
+	publishedAt := time.Now().UTC()
+	if req.PublishAt != nil {
+		status, message = "scheduled", "Article successfully scheduled"
+		publishedAt = req.PublishAt.UTC()
+	}
+	article := &data.Article{
+		AuthorID:    api.contextGetUser(r).ID,
+		Title:       req.Title,
+		Content:     req.Content,
+		CategoryID:  req.CategoryID,
+		Status:      status,
+		TagIDs:      req.TagIDs,
+		PublishedAt: &publishedAt,
+	}
 	if req.ID != nil {
-		_, err = api.models.Articles.Update(ctx, &data.Article{
-			ID:         *req.ID,
-			Title:      req.Title,
-			Content:    req.Content,
-			CategoryID: req.CategoryID,
-			Status:     "published",
-			TagIDs:     req.TagIDs,
-		})
+		current, err := api.models.Articles.GetByID(ctx, *req.ID)
+		if err != nil {
+			api.handleDBError(w, r, err)
+			return
+		}
+		if !api.isArticleAuthor(w, r, current) {
+			return
+		}
+		version, ifMatch, err := api.expectedVersion(r, req.Version, current.Version)
+		if err != nil {
+			api.badRequestResponse(w, err, err.Error())
+			return
+		}
+		article.ID = current.ID
+		article.Version = version
+		_, err = api.models.Articles.Update(ctx, article)
+		if err != nil {
+			api.updateErrorResponse(w, r, err, ifMatch)
+			return
+		}
+		api.setETag(w, article.Version)
 	} else {
-		_, err = api.models.Articles.Create(ctx, &data.Article{
-			AuthorID:   req.AuthorID,
-			Title:      req.Title,
-			Content:    req.Content,
-			CategoryID: req.CategoryID,
-			Status:     "published",
-			TagIDs:     req.TagIDs,
-		})
+		_, err = api.models.Articles.Create(ctx, article)
 		if err != nil {
 			api.handleDBError(w, r, err)
 			return
 		}
 	}
-	api.writeSuccessResponse(w, http.StatusCreated, nil, "Article successfully published")
+	api.writeSuccessResponse(w, http.StatusCreated, nil, message)
 }
 
 type CreateDraftRequest struct {
 	ID         *string `json:"id"`
-	AuthorID   string  `json:"author_id" validate:"required"`
 	Title      *string `json:"title"`
 	Content    *string `json:"content"`
 	TagIDs     []int   `json:"tag_ids"`
 	CategoryID *int    `json:"category_id"`
+	Version    *int    `json:"version"`
+}
+
+// legacyDraftHandler serves POST /v1/articles/draft, the path drafts were
+// created at before /v1/drafts.
+func (api *API) legacyDraftHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := api.readParam(r, "id")
+	if err != nil || id != "draft" {
+		api.notFoundResponse(w, "The requested resource could not be found")
+		return
+	}
+	api.authorsOnly(api.createDraftHandler)(w, r)
 }
 
 func (api *API) createDraftHandler(w http.ResponseWriter, r *http.Request) {
@@ -98,14 +143,28 @@ func (api *API) createDraftHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	article := &data.Article{
-		AuthorID: req.AuthorID,
-		TagIDs:   req.TagIDs,
+		AuthorID: api.contextGetUser(r).ID,
 	}
 
+	ifMatch := false
 	if req.ID != nil {
-		article.ID = *req.ID
+		// start from the stored draft so fields left out of the request are kept
+		article, err = api.models.Articles.GetByID(ctx, *req.ID)
+		if err != nil {
+			api.handleDBError(w, r, err)
+			return
+		}
+		if !api.isArticleAuthor(w, r, article) {
+			return
+		}
+		article.Version, ifMatch, err = api.expectedVersion(r, req.Version, article.Version)
+		if err != nil {
+			api.badRequestResponse(w, err, err.Error())
+			return
+		}
 	}
 
+	article.TagIDs = req.TagIDs
 	if req.Title != nil {
 		article.Title = *req.Title
 	}
@@ -116,13 +175,15 @@ func (api *API) createDraftHandler(w http.ResponseWriter, r *http.Request) {
 		article.CategoryID = *req.CategoryID
 	}
 	article.Status = "draft"
+	article.PublishedAt = nil
 	if req.ID != nil {
 		updateInfo, err := api.models.Articles.Update(ctx, article)
 		if err != nil {
-			api.handleDBError(w, r, err)
+			api.updateErrorResponse(w, r, err, ifMatch)
 			return
 		}
-		api.writeSuccessResponse(w, http.StatusOK, envelope{"data": updateInfo}, "Draft successfully updated")
+		api.setETag(w, article.Version)
+		api.writeSuccessResponse(w, http.StatusOK, envelope{"data": updateInfo, "version": article.Version}, "Draft successfully updated")
 	} else {
 		_, err := api.models.Articles.Create(ctx, article)
 		if err != nil {
@@ -140,6 +201,159 @@ func (api *API) deleteArticleHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func (api *API) getArticleDetailsHandler(w http.ResponseWriter, r *http.Request) {
-	//ctx, cancel := api.CreateContext()
-	//defer cancel()
+	ctx, cancel := api.CreateContext()
+	defer cancel()
+
+	id, err := api.readParam(r, "id")
+	if err != nil {
+		api.badRequestResponse(w, err, "ID parameter not provided")
+		return
+	}
+	if id == "trending" {
+		api.listTrendingArticlesHandler(w, r)
+		return
+	}
+	api.writeArticleDetails(ctx, w, r, id)
+}
+
+// getArticleBySlugHandler serves /v1/@:username/:slug. Slugs an article has
+// since outgrown are answered with a permanent redirect to the current one.
+func (api *API) getArticleBySlugHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := api.CreateContext()
+	defer cancel()
+
+	username, err := api.readParam(r, "username")
+	if err != nil {
+		api.badRequestResponse(w, err, "Username parameter not provided")
+		return
+	}
+	slug, err := api.readParam(r, "slug")
+	if err != nil {
+		api.badRequestResponse(w, err, "Slug parameter not provided")
+		return
+	}
+	id, currentSlug, err := api.models.Articles.ResolveSlug(ctx, strings.ToLower(username), slug)
+	if err != nil {
+		api.handleDBError(w, r, err)
+		return
+	}
+	if currentSlug != slug {
+		location := fmt.Sprintf("/v1/@%s/%s", url.PathEscape(username), url.PathEscape(currentSlug))
+		http.Redirect(w, r, location, http.StatusMovedPermanently)
+		return
+	}
+	api.writeArticleDetails(ctx, w, r, id)
+}
+
+func (api *API) writeArticleDetails(ctx context.Context, w http.ResponseWriter, r *http.Request, id string) {
+	viewerID := ""
+	if viewer, ok := api.contextLookupUser(r); ok {
+		viewerID = viewer.ID
+	}
+	article, err := api.models.Articles.GetDetails(ctx, id, viewerID)
+	if err != nil {
+		api.handleDBError(w, r, err)
+		return
+	}
+	// unpublished articles are reported as missing to everyone but their author
+	if article.Status != "published" && article.AuthorID != viewerID {
+		api.notFoundResponse(w, "Article not found")
+		return
+	}
+	api.setETag(w, article.Version)
+	api.writeSuccessResponse(w, http.StatusOK, envelope{"article": article}, "")
+}
+
+var articleSortSafeList = []string{
+	"title", "created_at", "updated_at", "published_at",
+	"-title", "-created_at", "-updated_at", "-published_at",
+}
+
+// readArticleFilters parses the pagination, sorting and filtering query
+// parameters shared by the article list endpoints.
+func (api *API) readArticleFilters(r *http.Request) (data.Filters, error) {
+	qs := r.URL.Query()
+	filters := data.Filters{
+		Sort:         api.readString(qs, "sort", "-published_at"),
+		SortSafeList: articleSortSafeList,
+		Search:       strings.TrimSpace(api.readString(qs, "search", "")),
+		Category:     api.readString(qs, "category", ""),
+		Tags:         api.readCSV(qs, "tags", []string{}),
+		AuthorID:     api.readString(qs, "author_id", ""),
+	}
+	var err error
+	filters.Page, err = api.readInt(qs, "page", 1)
+	if err != nil {
+		return filters, err
+	}
+	filters.PageSize, err = api.readInt(qs, "page_size", 20)
+	if err != nil {
+		return filters, err
+	}
+	return filters, nil
+}
+
+func (api *API) validateFilters(w http.ResponseWriter, filters data.Filters) bool {
+	v := validator.New()
+	if validationError := v.Struct(filters); validationError != nil {
+		api.failedValidationResponse(w, validationError)
+		return false
+	}
+	if !filters.SortIsSafe() {
+		message := fmt.Sprintf("sort must be one of: %s", strings.Join(filters.SortSafeList, ", "))
+		api.badRequestResponse(w, errors.New("unsafe sort parameter"), message)
+		return false
+	}
+	return true
+}
+
+func (api *API) listArticlesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := api.CreateContext()
+	defer cancel()
+
+	filters, err := api.readArticleFilters(r)
+	if err != nil {
+		api.badRequestResponse(w, err, err.Error())
+		return
+	}
+	// drafts are never listed publicly
+	filters.Status = "published"
+	if !api.validateFilters(w, filters) {
+		return
+	}
+	articles, metadata, err := api.models.Articles.GetAll(ctx, filters)
+	if err != nil {
+		api.handleDBError(w, r, err)
+		return
+	}
+	api.successResponseWithPagination(w, http.StatusOK, envelope{"articles": articles}, metadata, "")
+}
+
+var searchSortSafeList = []string{"-rank", "-published_at", "published_at"}
+
+func (api *API) searchArticlesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := api.CreateContext()
+	defer cancel()
+
+	filters, err := api.readArticleFilters(r)
+	if err != nil {
+		api.badRequestResponse(w, err, err.Error())
+		return
+	}
+	filters.Search = strings.TrimSpace(api.readString(r.URL.Query(), "q", ""))
+	filters.Sort = api.readString(r.URL.Query(), "sort", "-rank")
+	filters.SortSafeList = searchSortSafeList
+	if filters.Search == "" {
+		api.badRequestResponse(w, errors.New("missing search query"), "q parameter must be provided")
+		return
+	}
+	if !api.validateFilters(w, filters) {
+		return
+	}
+	results, metadata, err := api.models.Articles.Search(ctx, filters)
+	if err != nil {
+		api.handleDBError(w, r, err)
+		return
+	}
+	api.successResponseWithPagination(w, http.StatusOK, envelope{"results": results}, metadata, "")
 }
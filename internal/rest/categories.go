@@ -9,10 +9,11 @@ import (
 )
 
 func (api *API) initializeCategoryRoutes() {
-	api.router.HandlerFunc(http.MethodPost, "/v1/categories", api.createCategoryHandler)
+	editorsOnly := api.requireRole(data.RoleEditor, data.RoleAdmin)
+	api.router.HandlerFunc(http.MethodPost, "/v1/categories", editorsOnly(api.createCategoryHandler))
 	api.router.HandlerFunc(http.MethodGet, "/v1/categories", api.getCategoriesHandler)
-	api.router.HandlerFunc(http.MethodPatch, "/v1/categories", api.updateCategoryNameHandler)
-	api.router.HandlerFunc(http.MethodDelete, "/v1/categories/:id", api.deleteCategoryHandler)
+	api.router.HandlerFunc(http.MethodPatch, "/v1/categories", editorsOnly(api.updateCategoryNameHandler))
+	api.router.HandlerFunc(http.MethodDelete, "/v1/categories/:id", editorsOnly(api.deleteCategoryHandler))
 }
 
 type CreateCategoryRequest struct {
@@ -0,0 +1,234 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"github.com/rx-rz/65ch/internal/data"
+	"net/http"
+)
+
+func (api *API) initializeCommentRoutes() {
+	api.router.HandlerFunc(http.MethodGet, "/v1/articles/:id/comments", api.optionalAccess(api.listArticleCommentsHandler))
+	api.router.HandlerFunc(http.MethodPost, "/v1/articles/:id/comments", api.activatedOnly(api.commentOnArticleHandler))
+	api.router.HandlerFunc(http.MethodPatch, "/v1/comments/:id", api.authorizedAccessOnly(api.editCommentHandler))
+	api.router.HandlerFunc(http.MethodDelete, "/v1/comments/:id", api.authorizedAccessOnly(api.deleteCommentHandler))
+}
+
+var commentSortSafeList = []string{"oldest", "newest", "top"}
+
+func (api *API) listArticleCommentsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := api.CreateContext()
+	defer cancel()
+
+	articleID, err := api.readParam(r, "id")
+	if err != nil {
+		api.badRequestResponse(w, err, "ID parameter not provided")
+		return
+	}
+	article, err := api.models.Articles.GetByID(ctx, articleID)
+	if err != nil {
+		api.handleDBError(w, r, err)
+		return
+	}
+	// comments on an unpublished article are as hidden as the article is
+	viewer, _ := api.contextLookupUser(r)
+	if article.Status != "published" && (viewer == nil || viewer.ID != article.AuthorID) {
+		api.notFoundResponse(w, "Article not found")
+		return
+	}
+	qs := r.URL.Query()
+	filters := data.Filters{
+		Sort:         api.readString(qs, "sort", "oldest"),
+		SortSafeList: commentSortSafeList,
+	}
+	filters.Page, err = api.readInt(qs, "page", 1)
+	if err != nil {
+		api.badRequestResponse(w, err, err.Error())
+		return
+	}
+	filters.PageSize, err = api.readInt(qs, "page_size", 20)
+	if err != nil {
+		api.badRequestResponse(w, err, err.Error())
+		return
+	}
+	format := api.readString(qs, "format", "tree")
+	if format != "tree" && format != "flat" {
+		api.badRequestResponse(w, errors.New("invalid format"), "format must be one of: tree, flat")
+		return
+	}
+	if !api.validateFilters(w, filters) {
+		return
+	}
+
+	comments, metadata, err := api.models.Comments.ListThreads(ctx, articleID, filters)
+	if err != nil {
+		api.handleDBError(w, r, err)
+		return
+	}
+	if format == "flat" {
+		comments = data.FlattenCommentTree(comments)
+	}
+	api.successResponseWithPagination(w, http.StatusOK, envelope{"comments": comments}, metadata, "")
+}
+
+type CommentOnArticleRequest struct {
+	Content  string  `json:"content" validate:"required,max=10000"`
+	ParentID *string `json:"parent_id"`
+}
+
+func (api *API) commentOnArticleHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := api.CreateContext()
+	defer cancel()
+
+	articleID, err := api.readParam(r, "id")
+	if err != nil {
+		api.badRequestResponse(w, err, "ID parameter not provided")
+		return
+	}
+	var req CommentOnArticleRequest
+	err = api.readJSON(w, r, &req)
+	if err != nil {
+		api.badRequestResponse(w, err, err.Error())
+		return
+	}
+
+	v := validator.New()
+	if validationError := v.Struct(req); validationError != nil {
+		api.failedValidationResponse(w, validationError)
+		return
+	}
+
+	user := api.contextGetUser(r)
+	article, err := api.models.Articles.GetByID(ctx, articleID)
+	if err != nil {
+		api.handleDBError(w, r, err)
+		return
+	}
+	if article.Status != "published" && article.AuthorID != user.ID {
+		api.notFoundResponse(w, "Article not found")
+		return
+	}
+	var parent *data.Comment
+	if req.ParentID != nil {
+		parent, err = api.models.Comments.GetByID(ctx, *req.ParentID)
+		if err != nil {
+			api.handleDBError(w, r, err)
+			return
+		}
+		if parent.ArticleID != article.ID {
+			api.badRequestResponse(w, errors.New("parent comment belongs to another article"), "Parent comment belongs to another article")
+			return
+		}
+		if parent.Deleted {
+			api.badRequestResponse(w, errors.New("parent comment is deleted"), "You cannot reply to a deleted comment")
+			return
+		}
+	}
+
+	comment, err := api.models.Comments.Create(ctx, &data.Comment{
+		UserID:    user.ID,
+		ArticleID: article.ID,
+		ParentID:  req.ParentID,
+		Content:   req.Content,
+	})
+	if err != nil {
+		api.handleDBError(w, r, err)
+		return
+	}
+
+	// a reply notifies the parent's author; the article's author hears about
+	// every comment unless they are the one being replied to
+	if parent != nil {
+		api.createNotification(ctx, &data.Notification{
+			RecipientID: parent.UserID,
+			ActorID:     user.ID,
+			Type:        data.NotificationReply,
+			ArticleID:   &article.ID,
+			CommentID:   &parent.ID,
+		})
+	}
+	if parent == nil || parent.UserID != article.AuthorID {
+		api.createNotification(ctx, &data.Notification{
+			RecipientID: article.AuthorID,
+			ActorID:     user.ID,
+			Type:        data.NotificationComment,
+			ArticleID:   &article.ID,
+			CommentID:   &comment.ID,
+		})
+	}
+	api.writeSuccessResponse(w, http.StatusCreated, envelope{"comment": comment}, "Comment created successfully")
+}
+
+// readOwnedComment loads the comment named by the id path parameter and makes
+// sure the authenticated user wrote it. With allowModerators, editors and
+// admins may act on anyone's comment too.
+func (api *API) readOwnedComment(ctx context.Context, w http.ResponseWriter, r *http.Request, allowModerators bool) (*data.Comment, bool) {
+	id, err := api.readParam(r, "id")
+	if err != nil {
+		api.badRequestResponse(w, err, "ID parameter not provided")
+		return nil, false
+	}
+	comment, err := api.models.Comments.GetByID(ctx, id)
+	if err != nil {
+		api.handleDBError(w, r, err)
+		return nil, false
+	}
+	if comment.Deleted {
+		api.notFoundResponse(w, "Comment not found")
+		return nil, false
+	}
+	user := api.contextGetUser(r)
+	if comment.UserID != user.ID && !(allowModerators && hasRole(user, data.RoleEditor, data.RoleAdmin)) {
+		api.forbiddenResponse(w, "You are not the author of this comment")
+		return nil, false
+	}
+	return comment, true
+}
+
+type EditCommentRequest struct {
+	Content string `json:"content" validate:"required,max=10000"`
+}
+
+func (api *API) editCommentHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := api.CreateContext()
+	defer cancel()
+
+	var req EditCommentRequest
+	err := api.readJSON(w, r, &req)
+	if err != nil {
+		api.badRequestResponse(w, err, err.Error())
+		return
+	}
+	v := validator.New()
+	if validationError := v.Struct(req); validationError != nil {
+		api.failedValidationResponse(w, validationError)
+		return
+	}
+	comment, ok := api.readOwnedComment(ctx, w, r, false)
+	if !ok {
+		return
+	}
+	comment, err = api.models.Comments.UpdateContent(ctx, comment.ID, req.Content)
+	if err != nil {
+		api.handleDBError(w, r, err)
+		return
+	}
+	api.writeSuccessResponse(w, http.StatusOK, envelope{"comment": comment}, "Comment updated successfully")
+}
+
+func (api *API) deleteCommentHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := api.CreateContext()
+	defer cancel()
+
+	comment, ok := api.readOwnedComment(ctx, w, r, true)
+	if !ok {
+		return
+	}
+	deleteInfo, err := api.models.Comments.Delete(ctx, comment.ID)
+	if err != nil {
+		api.handleDBError(w, r, err)
+		return
+	}
+	api.writeSuccessResponse(w, http.StatusOK, envelope{"data": deleteInfo}, "Comment deleted successfully")
+}
@@ -8,7 +8,10 @@ import (
 
 type contextKey string
 
-const userContextKey = contextKey("user")
+const (
+	userContextKey    = contextKey("user")
+	sessionContextKey = contextKey("session")
+)
 
 func (api *API) contextSetUser(r *http.Request, user *data.User) *http.Request {
 	ctx := context.WithValue(r.Context(), userContextKey, user)
@@ -23,3 +26,25 @@ func (api *API) contextGetUser(r *http.Request) *data.User {
 	}
 	return user
 }
+
+// contextLookupUser is the non-panicking counterpart of contextGetUser, for
+// handlers behind optionalAccess.
+func (api *API) contextLookupUser(r *http.Request) (*data.User, bool) {
+	user, ok := r.Context().Value(userContextKey).(*data.User)
+	return user, ok
+}
+
+func (api *API) contextSetSessionID(r *http.Request, sessionID string) *http.Request {
+	ctx := context.WithValue(r.Context(), sessionContextKey, sessionID)
+	return r.WithContext(ctx)
+}
+
+// contextGetSessionID returns the session the request's access token was
+// issued for.
+func (api *API) contextGetSessionID(r *http.Request) string {
+	sessionID, ok := r.Context().Value(sessionContextKey).(string)
+	if !ok {
+		panic("missing session value in request context")
+	}
+	return sessionID
+}
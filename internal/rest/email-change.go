@@ -0,0 +1,67 @@
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"github.com/rx-rz/65ch/internal/data"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const emailChangeTokenTTL = 24 * time.Hour
+
+func (api *API) emailChangeURL(token string) string {
+	return fmt.Sprintf("%s/confirm-email?token=%s", api.env.ClientUrl, url.QueryEscape(token))
+}
+
+type ConfirmEmailChangeRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+func (api *API) confirmEmailChangeHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := api.CreateContext()
+	defer cancel()
+
+	var req ConfirmEmailChangeRequest
+	err := api.readJSON(w, r, &req)
+	if err != nil {
+		api.badRequestResponse(w, err, err.Error())
+		return
+	}
+
+	v := validator.New()
+	if validationError := v.Struct(req); validationError != nil {
+		api.failedValidationResponse(w, validationError)
+		return
+	}
+	change, err := api.models.EmailChanges.Confirm(ctx, req.Token)
+	if errors.Is(err, data.ErrRecordNotFound) {
+		api.writeErrorResponse(w, http.StatusUnprocessableEntity, ErrExpired, "Confirmation token is invalid or has expired", err)
+		return
+	}
+	if errors.Is(err, data.ErrDuplicateKey) {
+		api.conflictResponse(w, "User with email already exists")
+		return
+	}
+	if err != nil {
+		api.handleDBError(w, r, err)
+		return
+	}
+	user, err := api.models.Users.GetByEmail(ctx, change.NewEmail)
+	if err != nil {
+		api.handleDBError(w, r, err)
+		return
+	}
+	// both addresses hear about the change, so a hijacked account is noticed
+	for _, recipient := range []string{change.OldEmail, change.NewEmail} {
+		api.sendEmail(recipient, "email_changed.tmpl", map[string]any{
+			"firstName": user.FirstName,
+			"oldEmail":  change.OldEmail,
+			"newEmail":  change.NewEmail,
+			"changedAt": change.Timestamp.Format(time.RFC1123),
+		})
+	}
+	api.writeSuccessResponse(w, http.StatusOK, envelope{"data": change}, "User email updated successfully")
+}
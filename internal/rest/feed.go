@@ -0,0 +1,54 @@
+package rest
+
+import (
+	"errors"
+	"github.com/rx-rz/65ch/internal/data"
+	"net/http"
+)
+
+func (api *API) initializeFeedRoutes() {
+	api.router.HandlerFunc(http.MethodGet, "/v1/feed", api.authorizedAccessOnly(api.feedHandler))
+}
+
+// feedHandler serves the reader's feed a page at a time. Each page comes
+// with next_cursor, to be passed back as ?cursor= for the page after; it is
+// null on the last page.
+func (api *API) feedHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := api.CreateContext()
+	defer cancel()
+
+	qs := r.URL.Query()
+	limit, err := api.readInt(qs, "limit", 20)
+	if err != nil {
+		api.badRequestResponse(w, err, err.Error())
+		return
+	}
+	if limit < 1 || limit > 100 {
+		api.badRequestResponse(w, errors.New("limit out of range"), "limit must be between 1 and 100")
+		return
+	}
+	var cursor *data.FeedCursor
+	if s := api.readString(qs, "cursor", ""); s != "" {
+		cursor, err = data.DecodeFeedCursor(s)
+		if err != nil {
+			api.badRequestResponse(w, err, "cursor is not valid")
+			return
+		}
+	}
+
+	user := api.contextGetUser(r)
+	// one extra article tells us whether there is another page
+	items, err := api.models.Feed.Get(ctx, user.ID, cursor, limit+1)
+	if err != nil {
+		api.handleDBError(w, r, err)
+		return
+	}
+	var nextCursor *string
+	if len(items) > limit {
+		items = items[:limit]
+		last := items[limit-1]
+		next := data.FeedCursor{PublishedAt: *last.PublishedAt, ID: last.ID}.Encode()
+		nextCursor = &next
+	}
+	api.writeSuccessResponse(w, http.StatusOK, envelope{"articles": items, "next_cursor": nextCursor}, "")
+}
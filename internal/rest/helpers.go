@@ -1,12 +1,19 @@
 package rest
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/julienschmidt/httprouter"
+	"github.com/rx-rz/65ch/internal/password"
 	"io"
+	"net"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type envelope map[string]any
@@ -32,6 +39,8 @@ func (api *API) readJSON(w http.ResponseWriter, r *http.Request, dest any) error
 			err = fmt.Errorf("request input contains incorrect JSON type (at character %d, expected type %T)", unmarshalTypeError.Offset, unmarshalTypeError.Type)
 		case errors.Is(err, io.EOF):
 			err = errors.New("request input must not be empty")
+		case strings.HasPrefix(err.Error(), "json: unknown field "):
+			err = fmt.Errorf("request input contains unknown field %s", strings.TrimPrefix(err.Error(), "json: unknown field "))
 		case errors.As(err, &invalidUnmarshalError):
 			panic(err)
 		default:
@@ -61,6 +70,15 @@ func (api *API) writeJSON(w http.ResponseWriter, status int, data any) {
 	}
 }
 
+// clientIP is the address the request came from, without its port.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
 func (api *API) readParam(r *http.Request, name string) (string, error) {
 	params := httprouter.ParamsFromContext(r.Context())
 	param := params.ByName(name)
@@ -69,3 +87,119 @@ func (api *API) readParam(r *http.Request, name string) (string, error) {
 	}
 	return param, nil
 }
+
+func (api *API) readIntParam(r *http.Request, name string) (int, error) {
+	param, err := api.readParam(r, name)
+	if err != nil {
+		return 0, err
+	}
+	id, err := strconv.Atoi(param)
+	if err != nil {
+		return 0, fmt.Errorf("%s param must be an integer", name)
+	}
+	return id, nil
+}
+
+func (api *API) readString(qs url.Values, key string, defaultValue string) string {
+	s := qs.Get(key)
+	if s == "" {
+		return defaultValue
+	}
+	return s
+}
+
+func (api *API) readCSV(qs url.Values, key string, defaultValue []string) []string {
+	csv := qs.Get(key)
+	if csv == "" {
+		return defaultValue
+	}
+	return strings.Split(csv, ",")
+}
+
+func (api *API) readInt(qs url.Values, key string, defaultValue int) (int, error) {
+	s := qs.Get(key)
+	if s == "" {
+		return defaultValue, nil
+	}
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		return defaultValue, fmt.Errorf("%s must be an integer value", key)
+	}
+	return i, nil
+}
+
+// readIfMatch parses the version out of an If-Match header carrying one of the
+// ETags we hand out. It reports false when the header is absent or "*".
+func (api *API) readIfMatch(r *http.Request) (int, bool, error) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" || header == "*" {
+		return 0, false, nil
+	}
+	tag := strings.Trim(strings.TrimPrefix(header, "W/"), `"`)
+	version, err := strconv.Atoi(tag)
+	if err != nil {
+		return 0, false, errors.New("If-Match header must contain an ETag returned by this API")
+	}
+	return version, true, nil
+}
+
+// expectedVersion returns the version an update must be applied against. An
+// If-Match header wins over a version sent in the body; with neither, the
+// freshly loaded current version is used and the update is unconditional.
+func (api *API) expectedVersion(r *http.Request, bodyVersion *int, current int) (int, bool, error) {
+	version, ifMatch, err := api.readIfMatch(r)
+	if err != nil || ifMatch {
+		return version, ifMatch, err
+	}
+	if bodyVersion != nil {
+		return *bodyVersion, false, nil
+	}
+	return current, false, nil
+}
+
+func (api *API) setETag(w http.ResponseWriter, version int) {
+	w.Header().Set("ETag", strconv.Quote(strconv.Itoa(version)))
+}
+
+// background runs fn in its own goroutine, logging instead of crashing the
+// server if it panics.
+func (api *API) background(fn func()) {
+	go func() {
+		defer func() {
+			if err := recover(); err != nil {
+				api.logger.PrintError(fmt.Errorf("%s", err), nil)
+			}
+		}()
+		fn()
+	}()
+}
+
+// sendEmail delivers a templated email in the background so handlers never
+// wait on the mail server.
+func (api *API) sendEmail(recipient, templateFile string, data any) {
+	api.background(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		err := api.mailer.Send(ctx, recipient, templateFile, data)
+		if err != nil {
+			api.logger.PrintError(err, map[string]string{"template": templateFile})
+		}
+	})
+}
+
+// checkNewPassword holds a new password to the password policy, writing the
+// response and returning false if it falls short. personal is what the
+// password must not contain: the user's names, username and email.
+func (api *API) checkNewPassword(ctx context.Context, w http.ResponseWriter, r *http.Request, newPassword string, personal ...string) bool {
+	err := api.passwords.Check(ctx, newPassword, personal...)
+	var policyErr *password.Error
+	switch {
+	case err == nil:
+		return true
+	case errors.As(err, &policyErr):
+		api.failedValidationResponse(w, err)
+	default:
+		api.internalServerErrorResponse(w, r, err)
+	}
+	return false
+}
@@ -0,0 +1,14 @@
+package rest
+
+import "net/http"
+
+func (api *API) initializeKeyRoutes() {
+	api.router.HandlerFunc(http.MethodGet, "/.well-known/jwks.json", api.jwksHandler)
+}
+
+// jwksHandler publishes the token verification keys as a bare JWK set rather
+// than in the usual envelope, so standard JWT libraries can consume it.
+func (api *API) jwksHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	api.writeJSON(w, http.StatusOK, api.keyring.JWKS())
+}
@@ -0,0 +1,148 @@
+package rest
+
+import (
+	"context"
+	"github.com/rx-rz/65ch/internal/data"
+	"github.com/rx-rz/65ch/internal/lockout"
+	"strings"
+	"time"
+)
+
+// guards holds the failed-attempt policies for the credential endpoints.
+type guards struct {
+	loginAccount *lockout.Guard
+	loginIP      *lockout.Guard
+	reset        *lockout.Guard
+}
+
+func newGuards(store lockout.Store) guards {
+	return guards{
+		// one guesser against one account: 1s, 2s, 4s, 8s, then locked
+		loginAccount: &lockout.Guard{
+			Store:           store,
+			Threshold:       5,
+			BaseDelay:       time.Second,
+			MaxDelay:        30 * time.Second,
+			LockoutDuration: 15 * time.Minute,
+			Window:          time.Hour,
+			Now:             time.Now,
+		},
+		// one address spraying many accounts; no per-try delay, since
+		// several people can share an address
+		loginIP: &lockout.Guard{
+			Store:           store,
+			Threshold:       30,
+			LockoutDuration: 15 * time.Minute,
+			Window:          time.Hour,
+			Now:             time.Now,
+		},
+		// every reset request counts, successful or not, so a mailbox can't
+		// be flooded with reset emails
+		reset: &lockout.Guard{
+			Store:           store,
+			Threshold:       5,
+			BaseDelay:       time.Minute,
+			MaxDelay:        10 * time.Minute,
+			LockoutDuration: time.Hour,
+			Window:          time.Hour,
+			Now:             time.Now,
+		},
+	}
+}
+
+func accountKey(prefix, email string) string {
+	return prefix + ":account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipKey(prefix, ip string) string {
+	return prefix + ":ip:" + ip
+}
+
+// loginAttempt is a login or second-factor check counted up front against
+// the account and the address it came from.
+type loginAttempt struct {
+	email   string
+	ip      string
+	account lockout.Record
+	address lockout.Record
+	// locked is set when counting the attempt locked the account, which is
+	// only reported if the attempt then fails.
+	locked bool
+}
+
+// reserveLogin counts a login attempt for email from ip before any
+// credential is checked, so concurrent guesses can't all get through at
+// zero failures. A non-zero wait means the attempt was refused, with the
+// account's or the address's backoff, and nothing stays counted.
+func (api *API) reserveLogin(ctx context.Context, email, ip string) (*loginAttempt, time.Duration, error) {
+	attempt := &loginAttempt{email: email, ip: ip}
+	wait, record, locked, err := api.guards.loginAccount.Attempt(ctx, accountKey("login", email))
+	if err != nil || wait > 0 {
+		return nil, wait, err
+	}
+	attempt.account, attempt.locked = record, locked
+
+	wait, record, _, err = api.guards.loginIP.Attempt(ctx, ipKey("login", ip))
+	if err != nil || wait > 0 {
+		if refundErr := api.guards.loginAccount.Refund(ctx, accountKey("login", email), attempt.account); refundErr != nil {
+			api.logger.PrintError(refundErr, nil)
+		}
+		return nil, wait, err
+	}
+	attempt.address = record
+	return attempt, 0, nil
+}
+
+// loginFailed leaves a failed password or second-factor check counted, and
+// emails the owner when it locked their account.
+func (api *API) loginFailed(attempt *loginAttempt, user *data.User) {
+	if attempt.locked && user != nil {
+		api.logger.PrintInfo("account locked after failed logins", map[string]string{
+			"user_id": user.ID,
+			"ip":      attempt.ip,
+		})
+		api.sendEmail(user.Email, "account_locked.tmpl", map[string]any{
+			"firstName": user.FirstName,
+			"lockedFor": api.guards.loginAccount.LockoutDuration.String(),
+			"ip":        attempt.ip,
+			"resetURL":  api.env.ClientUrl + "/forgot-password",
+		})
+	}
+}
+
+// loginPassed takes the attempt back. A finished login forgets the
+// account's failures; one still waiting on its second factor keeps them,
+// so a known password doesn't buy unlimited code guesses. The address keeps
+// its earlier failures either way.
+func (api *API) loginPassed(ctx context.Context, attempt *loginAttempt, finished bool) {
+	var err error
+	if finished {
+		err = api.guards.loginAccount.Reset(ctx, accountKey("login", attempt.email))
+	} else {
+		err = api.guards.loginAccount.Refund(ctx, accountKey("login", attempt.email), attempt.account)
+	}
+	if err != nil {
+		api.logger.PrintError(err, nil)
+	}
+	if err = api.guards.loginIP.Refund(ctx, ipKey("login", attempt.ip), attempt.address); err != nil {
+		api.logger.PrintError(err, nil)
+	}
+}
+
+// reserveReset counts a reset request against the account and the address
+// it came from before anything is looked up. Every request counts, so
+// nothing is taken back unless the address has to wait after the account
+// was already counted.
+func (api *API) reserveReset(ctx context.Context, email, ip string) (time.Duration, error) {
+	wait, record, _, err := api.guards.reset.Attempt(ctx, accountKey("reset", email))
+	if err != nil || wait > 0 {
+		return wait, err
+	}
+	wait, _, _, err = api.guards.reset.Attempt(ctx, ipKey("reset", ip))
+	if err != nil || wait > 0 {
+		if refundErr := api.guards.reset.Refund(ctx, accountKey("reset", email), record); refundErr != nil {
+			api.logger.PrintError(refundErr, nil)
+		}
+	}
+	return wait, err
+}
@@ -0,0 +1,304 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/rx-rz/65ch/internal/auth"
+	"github.com/rx-rz/65ch/internal/data"
+	"github.com/rx-rz/65ch/internal/utils"
+	"net/http"
+	"time"
+)
+
+const (
+	// mfaPendingTokenType marks the token handed out between the password
+	// and second-factor steps of a login. It has no session, so
+	// authorizedAccessOnly never accepts it as an access token.
+	mfaPendingTokenType = "mfa_pending"
+	mfaPendingTokenTTL  = 5 * time.Minute
+	recoveryCodeCount   = 10
+)
+
+type MFAClaims struct {
+	jwt.RegisteredClaims
+	ID   string `json:"id"`
+	Type string `json:"typ"`
+}
+
+func (api *API) initializeMFARoutes() {
+	api.router.HandlerFunc(http.MethodPost, "/v1/auth/mfa/enroll", api.authorizedAccessOnly(api.enrollMFAHandler))
+	api.router.HandlerFunc(http.MethodPost, "/v1/auth/mfa/confirm", api.authorizedAccessOnly(api.confirmMFAHandler))
+	api.router.HandlerFunc(http.MethodPost, "/v1/auth/mfa/disable", api.authorizedAccessOnly(api.disableMFAHandler))
+	api.router.HandlerFunc(http.MethodPost, "/v1/auth/mfa/recovery-codes", api.authorizedAccessOnly(api.regenerateRecoveryCodesHandler))
+	api.router.HandlerFunc(http.MethodPost, "/v1/auth/mfa/verify", api.verifyMFAHandler)
+}
+
+// SecondFactorRequest carries either a code from the authenticator app or
+// one of the recovery codes.
+type SecondFactorRequest struct {
+	Code         string `json:"code" validate:"required_without=RecoveryCode,omitempty,numeric,len=6"`
+	RecoveryCode string `json:"recovery_code" validate:"required_without=Code,omitempty,max=20"`
+}
+
+// checkSecondFactor verifies a TOTP or recovery code for a user who has
+// two-factor enabled. Each TOTP step and each recovery code works once.
+func (api *API) checkSecondFactor(ctx context.Context, settings *data.MFASettings, req SecondFactorRequest) (bool, error) {
+	if !settings.Enabled || settings.Secret == nil {
+		return false, nil
+	}
+	if req.RecoveryCode != "" {
+		return api.models.MFA.UseRecoveryCode(ctx, settings.UserID, auth.HashRecoveryCode(req.RecoveryCode))
+	}
+	step, ok := api.totp.Verify(*settings.Secret, req.Code, settings.LastStep)
+	if !ok {
+		return false, nil
+	}
+	return api.models.MFA.UseStep(ctx, settings.UserID, step)
+}
+
+func (api *API) invalidSecondFactorResponse(w http.ResponseWriter) {
+	api.writeErrorResponse(w, http.StatusUnauthorized, ErrUnauthorized, "Invalid authentication code", nil)
+}
+
+// enrollMFAHandler starts two-factor enrollment. The secret does nothing
+// until it is confirmed with a first code.
+func (api *API) enrollMFAHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := api.CreateContext()
+	defer cancel()
+
+	user := api.contextGetUser(r)
+	if user.MFAEnabled {
+		api.writeErrorResponse(w, http.StatusConflict, ErrDuplicateEntry, "Two-factor authentication is already enabled", nil)
+		return
+	}
+	secret, err := api.totp.GenerateSecret()
+	if err != nil {
+		api.internalServerErrorResponse(w, r, err)
+		return
+	}
+	if _, err = api.models.MFA.SetPendingSecret(ctx, user.ID, secret); err != nil {
+		api.handleDBError(w, r, err)
+		return
+	}
+	api.writeSuccessResponse(w, http.StatusOK, envelope{
+		"secret":      secret,
+		"otpauth_uri": api.totp.URI(user.Email, secret),
+	}, "Scan the code with your authenticator app, then confirm with a code from it")
+}
+
+type ConfirmMFARequest struct {
+	Code string `json:"code" validate:"required,numeric,len=6"`
+}
+
+func (api *API) confirmMFAHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := api.CreateContext()
+	defer cancel()
+
+	var req ConfirmMFARequest
+	err := api.readJSON(w, r, &req)
+	if err != nil {
+		api.badRequestResponse(w, err, err.Error())
+		return
+	}
+
+	v := validator.New()
+	if validationError := v.Struct(req); validationError != nil {
+		api.failedValidationResponse(w, validationError)
+		return
+	}
+	user := api.contextGetUser(r)
+	settings, err := api.models.MFA.Get(ctx, user.ID)
+	if err != nil {
+		api.handleDBError(w, r, err)
+		return
+	}
+	if settings.Enabled {
+		api.writeErrorResponse(w, http.StatusConflict, ErrDuplicateEntry, "Two-factor authentication is already enabled", nil)
+		return
+	}
+	if settings.Secret == nil {
+		api.badRequestResponse(w, errors.New("enrollment not started"), "Start enrollment before confirming it")
+		return
+	}
+	step, ok := api.totp.Validate(*settings.Secret, req.Code)
+	if !ok {
+		api.invalidSecondFactorResponse(w)
+		return
+	}
+	codes, hashes, err := auth.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		api.internalServerErrorResponse(w, r, err)
+		return
+	}
+	if _, err = api.models.MFA.Enable(ctx, user.ID, step, hashes); err != nil {
+		api.handleDBError(w, r, err)
+		return
+	}
+	api.writeSuccessResponse(w, http.StatusOK, envelope{"recovery_codes": codes}, "Two-factor authentication enabled. Store these recovery codes somewhere safe; they will not be shown again")
+}
+
+type DisableMFARequest struct {
+	Password string `json:"password" validate:"required"`
+	SecondFactorRequest
+}
+
+func (api *API) disableMFAHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := api.CreateContext()
+	defer cancel()
+
+	var req DisableMFARequest
+	err := api.readJSON(w, r, &req)
+	if err != nil {
+		api.badRequestResponse(w, err, err.Error())
+		return
+	}
+
+	v := validator.New()
+	if validationError := v.Struct(req); validationError != nil {
+		api.failedValidationResponse(w, validationError)
+		return
+	}
+	user := api.contextGetUser(r)
+	if !utils.CheckPasswordHash(req.Password, user.Password) {
+		api.badRequestResponse(w, errors.New("invalid password"), "Invalid details provided")
+		return
+	}
+	settings, err := api.models.MFA.Get(ctx, user.ID)
+	if err != nil {
+		api.handleDBError(w, r, err)
+		return
+	}
+	ok, err := api.checkSecondFactor(ctx, settings, req.SecondFactorRequest)
+	if err != nil {
+		api.handleDBError(w, r, err)
+		return
+	}
+	if !ok {
+		api.invalidSecondFactorResponse(w)
+		return
+	}
+	updateInfo, err := api.models.MFA.Disable(ctx, user.ID)
+	if err != nil {
+		api.handleDBError(w, r, err)
+		return
+	}
+	api.writeSuccessResponse(w, http.StatusOK, envelope{"data": updateInfo}, "Two-factor authentication disabled")
+}
+
+func (api *API) regenerateRecoveryCodesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := api.CreateContext()
+	defer cancel()
+
+	var req SecondFactorRequest
+	err := api.readJSON(w, r, &req)
+	if err != nil {
+		api.badRequestResponse(w, err, err.Error())
+		return
+	}
+
+	v := validator.New()
+	if validationError := v.Struct(req); validationError != nil {
+		api.failedValidationResponse(w, validationError)
+		return
+	}
+	user := api.contextGetUser(r)
+	settings, err := api.models.MFA.Get(ctx, user.ID)
+	if err != nil {
+		api.handleDBError(w, r, err)
+		return
+	}
+	ok, err := api.checkSecondFactor(ctx, settings, req)
+	if err != nil {
+		api.handleDBError(w, r, err)
+		return
+	}
+	if !ok {
+		api.invalidSecondFactorResponse(w)
+		return
+	}
+	codes, hashes, err := auth.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		api.internalServerErrorResponse(w, r, err)
+		return
+	}
+	if err = api.models.MFA.RegenerateRecoveryCodes(ctx, user.ID, hashes); err != nil {
+		api.handleDBError(w, r, err)
+		return
+	}
+	api.writeSuccessResponse(w, http.StatusOK, envelope{"recovery_codes": codes}, "Recovery codes regenerated. Earlier codes no longer work")
+}
+
+type VerifyMFARequest struct {
+	MFAToken string `json:"mfa_token" validate:"required"`
+	SecondFactorRequest
+}
+
+// verifyMFAHandler finishes a login that was paused for a second factor.
+func (api *API) verifyMFAHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := api.CreateContext()
+	defer cancel()
+
+	var req VerifyMFARequest
+	err := api.readJSON(w, r, &req)
+	if err != nil {
+		api.badRequestResponse(w, err, err.Error())
+		return
+	}
+
+	v := validator.New()
+	if validationError := v.Struct(req); validationError != nil {
+		api.failedValidationResponse(w, validationError)
+		return
+	}
+	claims := &MFAClaims{}
+	if err = api.keyring.Parse(req.MFAToken, claims); err != nil || claims.Type != mfaPendingTokenType {
+		api.invalidTokenResponse(w, r)
+		return
+	}
+	user, err := api.models.Users.GetByID(ctx, claims.ID)
+	if err != nil {
+		api.handleDBError(w, r, err)
+		return
+	}
+	attempt, wait, err := api.reserveLogin(ctx, user.Email, clientIP(r))
+	if err != nil {
+		api.internalServerErrorResponse(w, r, err)
+		return
+	}
+	if wait > 0 {
+		api.tooManyAttemptsResponse(w, wait)
+		return
+	}
+	settings, err := api.models.MFA.Get(ctx, user.ID)
+	if err != nil {
+		api.handleDBError(w, r, err)
+		return
+	}
+	ok, err := api.checkSecondFactor(ctx, settings, req.SecondFactorRequest)
+	if err != nil {
+		api.handleDBError(w, r, err)
+		return
+	}
+	if !ok {
+		api.loginFailed(attempt, user)
+		api.invalidSecondFactorResponse(w)
+		return
+	}
+	api.loginPassed(ctx, attempt, true)
+	tokens, err := api.startSession(ctx, w, r, user)
+	if err != nil {
+		api.handleDBError(w, r, err)
+		return
+	}
+	if req.RecoveryCode != "" {
+		remaining, err := api.models.MFA.RemainingRecoveryCodes(ctx, user.ID)
+		if err != nil {
+			api.handleDBError(w, r, err)
+			return
+		}
+		tokens["recovery_codes_remaining"] = remaining
+	}
+	api.writeSuccessResponse(w, http.StatusOK, tokens, "Login successful")
+}
@@ -1,50 +1,139 @@
 package rest
 
 import (
+	"errors"
 	"github.com/golang-jwt/jwt/v5"
-	"github.com/rx-rz/65ch/internal/utils"
+	"github.com/rx-rz/65ch/internal/data"
 	"net/http"
-	"os"
 	"strings"
 )
 
 type UserClaims struct {
 	jwt.RegisteredClaims
-	ID    string `json:"id"`
-	Email string `json:"email"`
+	ID        string `json:"id"`
+	Email     string `json:"email"`
+	SessionID string `json:"sid"`
+	Role      string `json:"role"`
+}
+
+var (
+	errMissingToken   = errors.New("missing authorization header")
+	errInvalidToken   = errors.New("invalid authorization token")
+	errRevokedSession = errors.New("session has been revoked")
+)
+
+// authenticate resolves the user behind the request's bearer token, and
+// rejects tokens whose session has since been signed out.
+func (api *API) authenticate(r *http.Request) (*data.User, *UserClaims, error) {
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		return nil, nil, errMissingToken
+	}
+	headerParts := strings.Split(authHeader, " ")
+	if len(headerParts) != 2 || headerParts[0] != "Bearer" {
+		return nil, nil, errInvalidToken
+	}
+	claims := &UserClaims{}
+	err := api.keyring.Parse(headerParts[1], claims)
+	if err != nil || claims.SessionID == "" {
+		return nil, nil, errInvalidToken
+	}
+	ctx, cancel := api.CreateContext()
+	defer cancel()
+	active, err := api.models.Sessions.IsActive(ctx, claims.SessionID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !active {
+		return nil, nil, errRevokedSession
+	}
+	user, err := api.models.Users.GetByID(ctx, claims.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return user, claims, nil
 }
 
 func (api *API) authorizedAccessOnly(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" {
+		user, claims, err := api.authenticate(r)
+		switch {
+		case errors.Is(err, errMissingToken):
 			api.unauthorizedResponse(w, r)
 			return
-		}
-		headerParts := strings.Split(r.Header.Get("Authorization"), " ")
-		if len(headerParts) == 0 {
-			api.unauthorizedResponse(w, r)
-			return
-		}
-		if len(headerParts) != 2 || headerParts[0] != "Bearer" {
+		case errors.Is(err, errInvalidToken):
 			api.invalidTokenResponse(w, r)
 			return
-		}
-		userClaims := &UserClaims{}
-		claims, err := utils.DecodeToken(headerParts[1], os.Getenv("JWT_SECRET"), userClaims)
-		if err != nil {
-			api.invalidTokenResponse(w, r)
+		case errors.Is(err, errRevokedSession):
+			api.writeErrorResponse(w, http.StatusUnauthorized, ErrUnauthorized, "This session has been signed out", err)
 			return
-		}
-		ctx, cancel := api.CreateContext()
-		defer cancel()
-		user, err := api.models.Users.GetByID(ctx, claims.ID)
-		if err != nil {
+		case err != nil:
 			api.handleDBError(w, r, err)
 			return
 		}
 		r = api.contextSetUser(r, user)
+		r = api.contextSetSessionID(r, claims.SessionID)
+		next.ServeHTTP(w, r)
+	}
+}
+
+// activatedOnly is authorizedAccessOnly for actions that need a verified
+// email address, such as publishing or commenting.
+func (api *API) activatedOnly(next http.HandlerFunc) http.HandlerFunc {
+	return api.authorizedAccessOnly(api.requireActivation(next))
+}
+
+// requireActivation is the check behind activatedOnly, for stacking inside
+// another middleware that has already authenticated the user.
+func (api *API) requireActivation(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := api.contextGetUser(r)
+		if !user.Activated {
+			api.forbiddenResponse(w, "You must activate your account to access this resource")
+			return
+		}
 		next.ServeHTTP(w, r)
 	}
+}
+
+// requireRole is authorizedAccessOnly for routes limited to some roles. The
+// role is checked against the freshly loaded user rather than the token's
+// claim, so a demotion takes effect immediately.
+func (api *API) requireRole(roles ...string) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return api.authorizedAccessOnly(func(w http.ResponseWriter, r *http.Request) {
+			if !hasRole(api.contextGetUser(r), roles...) {
+				api.forbiddenResponse(w, "You do not have permission to access this resource")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// authorsOnly limits a route to the roles that can write articles.
+func (api *API) authorsOnly(next http.HandlerFunc) http.HandlerFunc {
+	return api.requireRole(data.RoleAuthor, data.RoleEditor, data.RoleAdmin)(next)
+}
+
+func hasRole(user *data.User, roles ...string) bool {
+	for _, role := range roles {
+		if user.Role == role {
+			return true
+		}
+	}
+	return false
+}
 
+// optionalAccess attaches the user to the request context when a valid token
+// is sent, and otherwise lets the request through anonymously.
+func (api *API) optionalAccess(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, claims, err := api.authenticate(r)
+		if err == nil {
+			r = api.contextSetUser(r, user)
+			r = api.contextSetSessionID(r, claims.SessionID)
+		}
+		next.ServeHTTP(w, r)
+	}
 }
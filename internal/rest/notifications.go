@@ -1,13 +1,124 @@
 package rest
 
-func (api *API) createNotificationHandler() {
+import (
+	"context"
+	"github.com/rx-rz/65ch/internal/data"
+	"net/http"
+)
 
+func (api *API) initializeNotificationRoutes() {
+	api.router.HandlerFunc(http.MethodGet, "/v1/notifications", api.authorizedAccessOnly(api.listNotificationsHandler))
+	api.router.HandlerFunc(http.MethodGet, "/v1/notifications/unread-count", api.authorizedAccessOnly(api.unreadNotificationCountHandler))
+	api.router.HandlerFunc(http.MethodPost, "/v1/notifications/read-all", api.authorizedAccessOnly(api.markAllNotificationsAsReadHandler))
+	api.router.HandlerFunc(http.MethodPatch, "/v1/notifications/:id/read", api.authorizedAccessOnly(api.markNotificationAsReadHandler))
+	api.router.HandlerFunc(http.MethodDelete, "/v1/notifications/:id", api.authorizedAccessOnly(api.deleteNotificationHandler))
 }
 
-func (api *API) deleteNotificationHandler() {
+// createNotification records a notification as a side effect of another
+// action. Failures are logged rather than returned, so a broken notification
+// never fails the like, follow or comment that triggered it.
+func (api *API) createNotification(ctx context.Context, notification *data.Notification) {
+	if notification.RecipientID == notification.ActorID {
+		return
+	}
+	err := api.models.Notifications.Create(ctx, notification)
+	if err != nil {
+		api.logger.PrintError(err, map[string]string{
+			"notification_type": notification.Type,
+			"recipient_id":      notification.RecipientID,
+		})
+	}
+}
+
+func (api *API) listNotificationsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := api.CreateContext()
+	defer cancel()
+
+	qs := r.URL.Query()
+	filters := data.Filters{Sort: "-latest_at", SortSafeList: []string{"-latest_at"}}
+	var err error
+	filters.Page, err = api.readInt(qs, "page", 1)
+	if err != nil {
+		api.badRequestResponse(w, err, err.Error())
+		return
+	}
+	filters.PageSize, err = api.readInt(qs, "page_size", 20)
+	if err != nil {
+		api.badRequestResponse(w, err, err.Error())
+		return
+	}
+	if !api.validateFilters(w, filters) {
+		return
+	}
+	unreadOnly := api.readString(qs, "unread", "false") == "true"
+
+	user := api.contextGetUser(r)
+	groups, metadata, err := api.models.Notifications.ListGroups(ctx, user.ID, unreadOnly, filters)
+	if err != nil {
+		api.handleDBError(w, r, err)
+		return
+	}
+	api.successResponseWithPagination(w, http.StatusOK, envelope{"notifications": groups}, metadata, "")
+}
+
+func (api *API) unreadNotificationCountHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := api.CreateContext()
+	defer cancel()
+
+	user := api.contextGetUser(r)
+	count, err := api.models.Notifications.UnreadCount(ctx, user.ID)
+	if err != nil {
+		api.handleDBError(w, r, err)
+		return
+	}
+	api.writeSuccessResponse(w, http.StatusOK, envelope{"unread_count": count}, "")
+}
+
+func (api *API) markNotificationAsReadHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := api.CreateContext()
+	defer cancel()
+
+	id, err := api.readParam(r, "id")
+	if err != nil {
+		api.badRequestResponse(w, err, "ID parameter not provided")
+		return
+	}
+	user := api.contextGetUser(r)
+	updateInfo, err := api.models.Notifications.MarkGroupRead(ctx, user.ID, id)
+	if err != nil {
+		api.handleDBError(w, r, err)
+		return
+	}
+	api.writeSuccessResponse(w, http.StatusOK, envelope{"data": updateInfo}, "Notification marked as read")
+}
+
+func (api *API) markAllNotificationsAsReadHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := api.CreateContext()
+	defer cancel()
 
+	user := api.contextGetUser(r)
+	updateInfo, err := api.models.Notifications.MarkAllRead(ctx, user.ID)
+	if err != nil {
+		api.handleDBError(w, r, err)
+		return
+	}
+	api.writeSuccessResponse(w, http.StatusOK, envelope{"data": updateInfo}, "All notifications marked as read")
 }
 
-func (api *API) markNotificationAsReadHandler() {
+func (api *API) deleteNotificationHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := api.CreateContext()
+	defer cancel()
 
+	id, err := api.readParam(r, "id")
+	if err != nil {
+		api.badRequestResponse(w, err, "ID parameter not provided")
+		return
+	}
+	user := api.contextGetUser(r)
+	deleteInfo, err := api.models.Notifications.DeleteGroup(ctx, user.ID, id)
+	if err != nil {
+		api.handleDBError(w, r, err)
+		return
+	}
+	api.writeSuccessResponse(w, http.StatusOK, envelope{"data": deleteInfo}, "Notification deleted successfully")
 }
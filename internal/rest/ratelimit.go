@@ -0,0 +1,74 @@
+package rest
+
+import (
+	"github.com/rx-rz/65ch/internal/ratelimit"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// rateLimits are the per-minute budgets for each class of route.
+type rateLimits struct {
+	store ratelimit.Store
+	auth  ratelimit.Limit
+	write ratelimit.Limit
+	read  ratelimit.Limit
+}
+
+// rateLimitClass sorts a request into the auth, write or read budget.
+func rateLimitClass(r *http.Request) string {
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/v1/auth/"):
+		return "auth"
+	case r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions:
+		return "read"
+	default:
+		return "write"
+	}
+}
+
+// rateLimitKey identifies who a request is charged to: the user when it
+// carries a valid access token, otherwise the address it came from. Only
+// the token's signature is checked here; authentication proper happens in
+// authorizedAccessOnly.
+func (api *API) rateLimitKey(r *http.Request) string {
+	headerParts := strings.Split(r.Header.Get("Authorization"), " ")
+	if len(headerParts) == 2 && headerParts[0] == "Bearer" {
+		claims := &UserClaims{}
+		if err := api.keyring.Parse(headerParts[1], claims); err == nil && claims.ID != "" {
+			return "user:" + claims.ID
+		}
+	}
+	return "ip:" + clientIP(r)
+}
+
+// rateLimit throttles every request with a token bucket per route class and
+// caller, and reports the caller's budget in X-RateLimit-* headers.
+func (api *API) rateLimit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		class := rateLimitClass(r)
+		limit := api.rateLimits.read
+		switch class {
+		case "auth":
+			limit = api.rateLimits.auth
+		case "write":
+			limit = api.rateLimits.write
+		}
+		result, err := api.rateLimits.store.Take(r.Context(), class+":"+api.rateLimitKey(r), limit)
+		if err != nil {
+			// an unavailable limiter shouldn't take the API down with it
+			api.logger.PrintError(err, map[string]string{"request_url": r.URL.String()})
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Set("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		w.Header().Set("X-RateLimit-Reset", strconv.Itoa(int(result.ResetAfter.Round(time.Second).Seconds())))
+		if !result.Allowed {
+			api.rateLimitExceededResponse(w, result.RetryAfter, "Rate limit exceeded. Slow down and try again later")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -4,7 +4,9 @@ import (
 	"errors"
 	"github.com/rx-rz/65ch/internal/data"
 	"github.com/rx-rz/65ch/internal/utils"
+	"math"
 	"net/http"
+	"strconv"
 	"time"
 )
 
@@ -42,6 +44,10 @@ const (
 	ErrUnauthorized      ErrorCode = "UNAUTHORIZED"
 	ErrForbidden         ErrorCode = "FORBIDDEN"
 	ErrDuplicateEntry    ErrorCode = "DUPLICATE_ENTRY"
+	ErrEditConflict      ErrorCode = "EDIT_CONFLICT"
+	ErrPrecondition      ErrorCode = "PRECONDITION_FAILED"
+	ErrRateLimited       ErrorCode = "RATE_LIMITED"
+	ErrTooManyAttempts   ErrorCode = "TOO_MANY_ATTEMPTS"
 	ErrValidation        ErrorCode = "VALIDATION_ERROR"
 	ErrDatabaseOperation ErrorCode = "DATABASE_ERROR"
 	ErrInternal          ErrorCode = "INTERNAL_ERROR"
@@ -79,10 +85,50 @@ func (api *API) failedValidationResponse(w http.ResponseWriter, err error) {
 	api.writeErrorResponse(w, http.StatusBadRequest, ErrBadRequest, utils.GetValidationErrors(err), err)
 }
 
+func (api *API) unprocessableEntityResponse(w http.ResponseWriter, message string) {
+	api.writeErrorResponse(w, http.StatusUnprocessableEntity, ErrInvalidInput, message, nil)
+}
+
+func (api *API) forbiddenResponse(w http.ResponseWriter, message string) {
+	api.writeErrorResponse(w, http.StatusForbidden, ErrForbidden, message, nil)
+}
+
+func (api *API) notFoundResponse(w http.ResponseWriter, message string) {
+	api.writeErrorResponse(w, http.StatusNotFound, ErrNotFound, message, nil)
+}
+
 func (api *API) conflictResponse(w http.ResponseWriter, message string) {
 	api.writeErrorResponse(w, http.StatusConflict, ErrDuplicateEntry, message, nil)
 }
 
+// rateLimitExceededResponse tells the client to back off, and for how long.
+func (api *API) rateLimitExceededResponse(w http.ResponseWriter, retryAfter time.Duration, message string) {
+	setRetryAfter(w, retryAfter)
+	api.writeErrorResponse(w, http.StatusTooManyRequests, ErrRateLimited, message, nil)
+}
+
+// tooManyAttemptsResponse is for credentials checks that have failed too
+// often, as opposed to plain request throttling.
+func (api *API) tooManyAttemptsResponse(w http.ResponseWriter, retryAfter time.Duration) {
+	setRetryAfter(w, retryAfter)
+	api.writeErrorResponse(w, http.StatusTooManyRequests, ErrTooManyAttempts, "Too many failed attempts. Try again later", nil)
+}
+
+func setRetryAfter(w http.ResponseWriter, retryAfter time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+}
+
+// updateErrorResponse reports a failed versioned update. Conflicts against an
+// If-Match precondition are 412s; conflicts against a version sent in the body
+// fall through to handleDBError as 409s.
+func (api *API) updateErrorResponse(w http.ResponseWriter, r *http.Request, err error, ifMatch bool) {
+	if ifMatch && errors.Is(err, data.ErrEditConflict) {
+		api.writeErrorResponse(w, http.StatusPreconditionFailed, ErrPrecondition, "The resource was modified since it was fetched", err)
+		return
+	}
+	api.handleDBError(w, r, err)
+}
+
 func (api *API) internalServerErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
 	api.logError(r, err)
 	api.errorResponse(w, r, http.StatusInternalServerError, "The server encountered a problem and could not process your request", true)
@@ -97,7 +143,7 @@ func (api *API) handleDBError(w http.ResponseWriter, r *http.Request, err error)
 			// api.writeErrorResponse(w, http.StatusNotFound, ErrNotFound, data.ErrRecordNotFound.Error(), dbErr)
 			api.writeErrorResponse(w, http.StatusNotFound, ErrNotFound, err.Error(), dbErr)
 		case errors.Is(err, data.ErrEditConflict):
-			api.writeErrorResponse(w, http.StatusConflict, ErrDuplicateEntry, err.Error(), dbErr)
+			api.writeErrorResponse(w, http.StatusConflict, ErrEditConflict, err.Error(), dbErr)
 		case errors.Is(err, data.ErrCheckConstraint):
 			api.writeErrorResponse(w, http.StatusBadRequest, ErrBadRequest, err.Error(), dbErr)
 		case errors.Is(err, data.ErrDuplicateKey):
@@ -140,4 +186,18 @@ func (api *API) writeErrorResponse(w http.ResponseWriter, status int, errorCode
 	api.writeJSON(w, status, response)
 }
 
-func (api *API) successResponseWithPagination() {}
+func (api *API) successResponseWithPagination(w http.ResponseWriter, status int, data any, metadata data.Metadata, message string) {
+	response := SuccessInfo{
+		Status:    "success",
+		Data:      data,
+		Message:   message,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		Pagination: &Pagination{
+			CurrentPage:  metadata.CurrentPge,
+			PageSize:     metadata.PageSize,
+			TotalPages:   metadata.LastPage,
+			TotalRecords: metadata.TotalRecords,
+		},
+	}
+	api.writeJSON(w, status, response)
+}
@@ -0,0 +1,205 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/rx-rz/65ch/internal/data"
+	"net/http"
+	"time"
+)
+
+const (
+	accessTokenTTL  = 15 * time.Minute
+	refreshTokenTTL = 30 * 24 * time.Hour
+	// refreshCookiePath keeps the refresh token cookie off every request
+	// except the ones that need it.
+	refreshCookiePath = "/v1/auth"
+)
+
+func (api *API) initializeSessionRoutes() {
+	// GET can't use a static "me" segment next to /v1/users/:id, so the
+	// listing is routed through the wildcard and only answers for "me".
+	api.router.HandlerFunc(http.MethodGet, "/v1/users/:id/sessions", api.authorizedAccessOnly(api.listSessionsHandler))
+	api.router.HandlerFunc(http.MethodDelete, "/v1/users/me/sessions", api.authorizedAccessOnly(api.revokeOtherSessionsHandler))
+	api.router.HandlerFunc(http.MethodDelete, "/v1/users/me/sessions/:id", api.authorizedAccessOnly(api.revokeSessionHandler))
+}
+
+// issueTokens signs a short-lived access token for the session and sets
+// both tokens as cookies. The returned envelope is the login response body.
+func (api *API) issueTokens(w http.ResponseWriter, user *data.User, session *data.Session, refreshToken string) (envelope, error) {
+	token, err := api.keyring.Sign(map[string]string{
+		"email":               user.Email,
+		"id":                  user.ID,
+		"sid":                 session.ID,
+		"role":                user.Role,
+		"first_name":          user.FirstName,
+		"last_name":           user.LastName,
+		"profile_picture_url": user.ProfilePicUrl,
+		"bio":                 user.Bio,
+	}, accessTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     "access_token",
+		Value:    fmt.Sprintf("Bearer %s", token),
+		Expires:  time.Now().Add(accessTokenTTL),
+		HttpOnly: true,
+		Secure:   false,
+		Path:     "/",
+	})
+	http.SetCookie(w, &http.Cookie{
+		Name:     "refresh_token",
+		Value:    refreshToken,
+		Expires:  session.ExpiresAt,
+		HttpOnly: true,
+		Secure:   false,
+		Path:     refreshCookiePath,
+	})
+	return envelope{
+		"token":         token,
+		"expires_in":    int(accessTokenTTL.Seconds()),
+		"refresh_token": refreshToken,
+	}, nil
+}
+
+// startSession signs the user in on the requesting device.
+func (api *API) startSession(ctx context.Context, w http.ResponseWriter, r *http.Request, user *data.User) (envelope, error) {
+	session, refreshToken, err := api.models.Sessions.Create(ctx, &data.Session{
+		UserID:    user.ID,
+		UserAgent: r.UserAgent(),
+		IPAddress: clientIP(r),
+	}, refreshTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+	return api.issueTokens(w, user, session, refreshToken)
+}
+
+func clearAuthCookies(w http.ResponseWriter) {
+	for name, path := range map[string]string{"access_token": "/", "refresh_token": refreshCookiePath} {
+		http.SetCookie(w, &http.Cookie{
+			Name:     name,
+			Value:    "",
+			Path:     path,
+			Expires:  time.Unix(0, 0),
+			MaxAge:   -1,
+			HttpOnly: true,
+		})
+	}
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// readRefreshToken takes the refresh token from its cookie, falling back to
+// the request body for clients that don't keep cookies.
+func (api *API) readRefreshToken(w http.ResponseWriter, r *http.Request) (string, error) {
+	if cookie, err := r.Cookie("refresh_token"); err == nil && cookie.Value != "" {
+		return cookie.Value, nil
+	}
+	var req RefreshTokenRequest
+	if err := api.readJSON(w, r, &req); err != nil {
+		return "", err
+	}
+	if req.RefreshToken == "" {
+		return "", errors.New("refresh token not provided")
+	}
+	return req.RefreshToken, nil
+}
+
+func (api *API) refreshTokenHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := api.CreateContext()
+	defer cancel()
+
+	refreshToken, err := api.readRefreshToken(w, r)
+	if err != nil {
+		api.badRequestResponse(w, err, err.Error())
+		return
+	}
+	session, newRefreshToken, err := api.models.Sessions.Rotate(ctx, refreshToken, r.UserAgent(), clientIP(r), refreshTokenTTL)
+	switch {
+	case errors.Is(err, data.ErrRefreshTokenReused):
+		clearAuthCookies(w)
+		api.writeErrorResponse(w, http.StatusUnauthorized, ErrUnauthorized, "Refresh token was already used. The session has been signed out", err)
+		return
+	case errors.Is(err, data.ErrRecordNotFound):
+		clearAuthCookies(w)
+		api.writeErrorResponse(w, http.StatusUnauthorized, ErrUnauthorized, "Refresh token is invalid or has expired", err)
+		return
+	case err != nil:
+		api.handleDBError(w, r, err)
+		return
+	}
+	user, err := api.models.Users.GetByID(ctx, session.UserID)
+	if err != nil {
+		api.handleDBError(w, r, err)
+		return
+	}
+	tokens, err := api.issueTokens(w, user, session, newRefreshToken)
+	if err != nil {
+		api.internalServerErrorResponse(w, r, err)
+		return
+	}
+	api.writeSuccessResponse(w, http.StatusOK, tokens, "Token refreshed successfully")
+}
+
+func (api *API) listSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := api.CreateContext()
+	defer cancel()
+
+	id, err := api.readParam(r, "id")
+	if err != nil || id != "me" {
+		api.notFoundResponse(w, "The requested resource could not be found")
+		return
+	}
+	user := api.contextGetUser(r)
+	sessions, err := api.models.Sessions.GetAllActiveForUser(ctx, user.ID)
+	if err != nil {
+		api.handleDBError(w, r, err)
+		return
+	}
+	currentID := api.contextGetSessionID(r)
+	for _, session := range sessions {
+		session.Current = session.ID == currentID
+	}
+	api.writeSuccessResponse(w, http.StatusOK, envelope{"sessions": sessions}, "")
+}
+
+func (api *API) revokeSessionHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := api.CreateContext()
+	defer cancel()
+
+	id, err := api.readParam(r, "id")
+	if err != nil {
+		api.badRequestResponse(w, err, "ID parameter not provided")
+		return
+	}
+	user := api.contextGetUser(r)
+	revokeInfo, err := api.models.Sessions.Revoke(ctx, user.ID, id)
+	if err != nil {
+		api.handleDBError(w, r, err)
+		return
+	}
+	if id == api.contextGetSessionID(r) {
+		clearAuthCookies(w)
+	}
+	api.writeSuccessResponse(w, http.StatusOK, envelope{"data": revokeInfo}, "Session revoked successfully")
+}
+
+// revokeOtherSessionsHandler signs the user out everywhere except the device
+// making the request.
+func (api *API) revokeOtherSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := api.CreateContext()
+	defer cancel()
+
+	user := api.contextGetUser(r)
+	count, err := api.models.Sessions.RevokeAllForUser(ctx, user.ID, api.contextGetSessionID(r))
+	if err != nil {
+		api.handleDBError(w, r, err)
+		return
+	}
+	api.writeSuccessResponse(w, http.StatusOK, envelope{"revoked": count}, "Signed out of all other sessions")
+}
@@ -9,11 +9,11 @@ import (
 )
 
 func (api *API) initializeTagRoutes() {
-	api.router.HandlerFunc(http.MethodPost, "/v1/tags", api.createTagHandler)
-	//api.router.HandlerFunc(http.MethodPatch, "/v1/tags", api.updateTagHandler)
+	editorsOnly := api.requireRole(data.RoleEditor, data.RoleAdmin)
+	api.router.HandlerFunc(http.MethodPost, "/v1/tags", editorsOnly(api.createTagHandler))
+	api.router.HandlerFunc(http.MethodPatch, "/v1/tags", editorsOnly(api.updateTagHandler))
 	api.router.HandlerFunc(http.MethodGet, "/v1/tags", api.getTagsHandler)
-	//api.router.HandlerFunc(http.MethodDelete, "/v1/tags/:id", api.deleteTagHandler)
-
+	api.router.HandlerFunc(http.MethodDelete, "/v1/tags/:id", editorsOnly(api.deleteTagHandler))
 }
 
 type CreateTagRequest struct {
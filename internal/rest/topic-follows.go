@@ -0,0 +1,116 @@
+package rest
+
+import (
+	"net/http"
+	"strconv"
+)
+
+func (api *API) initializeTopicFollowRoutes() {
+	api.router.HandlerFunc(http.MethodPost, "/v1/tags/:id/follow", api.authorizedAccessOnly(api.followTagHandler))
+	api.router.HandlerFunc(http.MethodDelete, "/v1/tags/:id/follow", api.authorizedAccessOnly(api.unfollowTagHandler))
+	api.router.HandlerFunc(http.MethodPost, "/v1/categories/:id/follow", api.authorizedAccessOnly(api.followCategoryHandler))
+	api.router.HandlerFunc(http.MethodDelete, "/v1/categories/:id/follow", api.authorizedAccessOnly(api.unfollowCategoryHandler))
+	// routed through the wildcard like the session listing, answering only
+	// for "me"
+	api.router.HandlerFunc(http.MethodGet, "/v1/users/:id/interests", api.authorizedAccessOnly(api.listInterestsHandler))
+}
+
+func (api *API) followTagHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := api.CreateContext()
+	defer cancel()
+
+	id, err := api.readIntParam(r, "id")
+	if err != nil {
+		api.badRequestResponse(w, err, err.Error())
+		return
+	}
+	if _, err = api.models.Tags.GetByID(ctx, strconv.Itoa(id)); err != nil {
+		api.handleDBError(w, r, err)
+		return
+	}
+	info, err := api.models.TagFollows.Follow(ctx, api.contextGetUser(r).ID, id)
+	if err != nil {
+		api.handleDBError(w, r, err)
+		return
+	}
+	api.writeSuccessResponse(w, http.StatusOK, envelope{"data": info}, "Tag followed successfully")
+}
+
+func (api *API) unfollowTagHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := api.CreateContext()
+	defer cancel()
+
+	id, err := api.readIntParam(r, "id")
+	if err != nil {
+		api.badRequestResponse(w, err, err.Error())
+		return
+	}
+	info, err := api.models.TagFollows.Unfollow(ctx, api.contextGetUser(r).ID, id)
+	if err != nil {
+		api.handleDBError(w, r, err)
+		return
+	}
+	api.writeSuccessResponse(w, http.StatusOK, envelope{"data": info}, "Tag unfollowed successfully")
+}
+
+func (api *API) followCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := api.CreateContext()
+	defer cancel()
+
+	id, err := api.readIntParam(r, "id")
+	if err != nil {
+		api.badRequestResponse(w, err, err.Error())
+		return
+	}
+	if _, err = api.models.Categories.GetByID(strconv.Itoa(id)); err != nil {
+		api.handleDBError(w, r, err)
+		return
+	}
+	info, err := api.models.CategoryFollows.Follow(ctx, api.contextGetUser(r).ID, id)
+	if err != nil {
+		api.handleDBError(w, r, err)
+		return
+	}
+	api.writeSuccessResponse(w, http.StatusOK, envelope{"data": info}, "Category followed successfully")
+}
+
+func (api *API) unfollowCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := api.CreateContext()
+	defer cancel()
+
+	id, err := api.readIntParam(r, "id")
+	if err != nil {
+		api.badRequestResponse(w, err, err.Error())
+		return
+	}
+	info, err := api.models.CategoryFollows.Unfollow(ctx, api.contextGetUser(r).ID, id)
+	if err != nil {
+		api.handleDBError(w, r, err)
+		return
+	}
+	api.writeSuccessResponse(w, http.StatusOK, envelope{"data": info}, "Category unfollowed successfully")
+}
+
+// listInterestsHandler returns the tags and categories the user follows.
+func (api *API) listInterestsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := api.CreateContext()
+	defer cancel()
+
+	id, err := api.readParam(r, "id")
+	if err != nil || id != "me" {
+		api.notFoundResponse(w, "The requested resource could not be found")
+		return
+	}
+	user := api.contextGetUser(r)
+	tags, err := api.models.TagFollows.GetAllForUser(ctx, user.ID)
+	if err != nil {
+		api.handleDBError(w, r, err)
+		return
+	}
+	categories, err := api.models.CategoryFollows.GetAllForUser(ctx, user.ID)
+	if err != nil {
+		api.handleDBError(w, r, err)
+		return
+	}
+	api.writeSuccessResponse(w, http.StatusOK, envelope{"tags": tags, "categories": categories}, "")
+}
@@ -0,0 +1,51 @@
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"github.com/rx-rz/65ch/internal/data"
+	"github.com/rx-rz/65ch/internal/trending"
+	"net/http"
+	"strings"
+)
+
+// listTrendingArticlesHandler serves GET /v1/articles/trending. Rankings
+// come from the trending worker, so they are as fresh as its last run.
+func (api *API) listTrendingArticlesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := api.CreateContext()
+	defer cancel()
+
+	qs := r.URL.Query()
+	window, ok := trending.ParseWindow(api.readString(qs, "window", trending.Windows[0].Name))
+	if !ok {
+		names := make([]string, len(trending.Windows))
+		for i, w := range trending.Windows {
+			names[i] = w.Name
+		}
+		message := fmt.Sprintf("window must be one of: %s", strings.Join(names, ", "))
+		api.badRequestResponse(w, errors.New("unknown trending window"), message)
+		return
+	}
+	filters := data.Filters{Sort: "-score", SortSafeList: []string{"-score"}}
+	var err error
+	filters.Page, err = api.readInt(qs, "page", 1)
+	if err != nil {
+		api.badRequestResponse(w, err, err.Error())
+		return
+	}
+	filters.PageSize, err = api.readInt(qs, "page_size", 20)
+	if err != nil {
+		api.badRequestResponse(w, err, err.Error())
+		return
+	}
+	if !api.validateFilters(w, filters) {
+		return
+	}
+
+	articles, metadata, err := api.models.Trending.GetAll(ctx, window.Name, api.readString(qs, "category", ""), filters)
+	if err != nil {
+		api.handleDBError(w, r, err)
+		return
+	}
+	api.successResponseWithPagination(w, http.StatusOK, envelope{"articles": articles, "window": window.Name}, metadata, "")
+}
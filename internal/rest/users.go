@@ -1,35 +1,46 @@
 package rest
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/go-playground/validator/v10"
 	"github.com/rx-rz/65ch/internal/data"
 	"github.com/rx-rz/65ch/internal/utils"
 	"net/http"
-	"os"
+	"net/url"
+	"strings"
 	"time"
 )
 
 func (api *API) initializeUserRoutes() {
 	api.router.HandlerFunc(http.MethodPost, "/v1/auth/login", api.loginUserHandler)
 	api.router.HandlerFunc(http.MethodPost, "/v1/auth/logout", api.logoutUserHandler)
+	api.router.HandlerFunc(http.MethodPost, "/v1/auth/refresh", api.refreshTokenHandler)
 	api.router.HandlerFunc(http.MethodPost, "/v1/auth/register", api.registerUserHandler)
 	api.router.HandlerFunc(http.MethodPost, "/v1/auth/request-password-reset", api.resetPasswordRequestHandler)
 	api.router.HandlerFunc(http.MethodPatch, "/v1/auth/reset-password", api.resetPasswordHandler)
+	api.router.HandlerFunc(http.MethodPut, "/v1/auth/activate", api.activateUserHandler)
+	api.router.HandlerFunc(http.MethodPost, "/v1/auth/resend-activation", api.resendActivationHandler)
+	api.router.HandlerFunc(http.MethodPut, "/v1/auth/confirm-email", api.confirmEmailChangeHandler)
 	api.router.HandlerFunc(http.MethodGet, "/v1/users/:id", api.authorizedAccessOnly(api.getUserDetailsHandler))
 	api.router.HandlerFunc(http.MethodPatch, "/v1/users/me", api.authorizedAccessOnly(api.updateUserDetailsHandler))
 	api.router.HandlerFunc(http.MethodDelete, "/v1/users/me", api.authorizedAccessOnly(api.deleteUserAccountHandler))
 	api.router.HandlerFunc(http.MethodPatch, "/v1/users/me/email", api.authorizedAccessOnly(api.updateUserEmailHandler))
 	api.router.HandlerFunc(http.MethodPatch, "/v1/users/me/password", api.authorizedAccessOnly(api.updateUserPasswordHandler))
-	api.router.HandlerFunc(http.MethodPost, "/v1/users/follow", api.authorizedAccessOnly(api.followUserHandler))
-	api.router.HandlerFunc(http.MethodPost, "/v1/users/unfollow", api.authorizedAccessOnly(api.unfollowUserHandler))
+	// unfollow is a POST because DELETE /v1/users/me already claims the
+	// segment after /v1/users/ for DELETE requests
+	api.router.HandlerFunc(http.MethodPost, "/v1/users/:id/follow", api.authorizedAccessOnly(api.followUserHandler))
+	api.router.HandlerFunc(http.MethodPost, "/v1/users/:id/unfollow", api.authorizedAccessOnly(api.unfollowUserHandler))
+	api.router.HandlerFunc(http.MethodGet, "/v1/users/:id/followers", api.authorizedAccessOnly(api.listFollowersHandler))
+	api.router.HandlerFunc(http.MethodGet, "/v1/users/:id/following", api.authorizedAccessOnly(api.listFollowingHandler))
 
 }
 
 type CreateUserRequest struct {
+	Username          string `json:"username" validate:"omitempty,min=3,max=30,alphanum"`
 	Email             string `json:"email" validate:"required,email,max=255"`
-	Password          string `json:"password" validate:"required,min=8,max=72"`
+	Password          string `json:"password" validate:"required,max=72"`
 	FirstName         string `json:"first_name" validate:"required,min=1,max=255"`
 	LastName          string `json:"last_name" validate:"required,min=1,max=255"`
 	Bio               string `json:"bio"`
@@ -52,6 +63,9 @@ func (api *API) registerUserHandler(w http.ResponseWriter, r *http.Request) {
 		api.failedValidationResponse(w, validationError)
 		return
 	}
+	if !api.checkNewPassword(ctx, w, r, req.Password, req.FirstName, req.LastName, req.Username, req.Email) {
+		return
+	}
 	hashedPassword, err := utils.HashPassword(req.Password)
 	if err != nil {
 		api.internalServerErrorResponse(w, r, err)
@@ -66,7 +80,21 @@ func (api *API) registerUserHandler(w http.ResponseWriter, r *http.Request) {
 		api.handleDBError(w, r, err)
 		return
 	}
+	req.Username = strings.ToLower(req.Username)
+	if req.Username == "" {
+		req.Username = utils.GenerateUsername(req.FirstName, req.LastName)
+	}
+	existingUser, err := api.models.Users.GetByUsername(ctx, req.Username)
+	if existingUser != nil {
+		api.conflictResponse(w, "Username is already taken")
+		return
+	}
+	if err != nil && !errors.Is(err, data.ErrRecordNotFound) {
+		api.handleDBError(w, r, err)
+		return
+	}
 	user = &data.User{
+		Username:      req.Username,
 		Email:         req.Email,
 		Password:      hashedPassword,
 		FirstName:     req.FirstName,
@@ -75,24 +103,36 @@ func (api *API) registerUserHandler(w http.ResponseWriter, r *http.Request) {
 		ProfilePicUrl: req.ProfilePictureUrl,
 	}
 
-	_, err = api.models.Users.Create(ctx, user)
+	newUser, activationToken, err := api.models.Users.Create(ctx, user, activationTokenTTL)
 	if err != nil {
 		api.handleDBError(w, r, err)
 		return
 	}
-	api.writeSuccessResponse(w, http.StatusCreated, nil, "User registered successfully")
+	api.sendEmail(newUser.Email, "welcome.tmpl", map[string]any{
+		"firstName":     newUser.FirstName,
+		"username":      newUser.Username,
+		"activationURL": api.activationURL(activationToken.Token),
+		"expiresIn":     activationTokenTTL.String(),
+	})
+	api.writeSuccessResponse(w, http.StatusCreated, nil, "User registered successfully. Check your email to activate your account")
 }
 
 func (api *API) logoutUserHandler(w http.ResponseWriter, r *http.Request) {
-	http.SetCookie(w, &http.Cookie{
-		Name:     "access_token",
-		Value:    "",
-		Path:     "/",
-		Expires:  time.Unix(0, 0),
-		MaxAge:   -1,
-		HttpOnly: true,
-	})
-	http.Redirect(w, r, "/login", http.StatusSeeOther)
+	ctx, cancel := api.CreateContext()
+	defer cancel()
+
+	refreshToken, err := api.readRefreshToken(w, r)
+	if err != nil {
+		api.badRequestResponse(w, err, err.Error())
+		return
+	}
+	_, err = api.models.Sessions.RevokeByToken(ctx, refreshToken)
+	if err != nil && !errors.Is(err, data.ErrRecordNotFound) {
+		api.handleDBError(w, r, err)
+		return
+	}
+	clearAuthCookies(w)
+	api.writeSuccessResponse(w, http.StatusOK, nil, "Logout successful")
 }
 
 type LoginUserRequest struct {
@@ -100,6 +140,10 @@ type LoginUserRequest struct {
 	Password string `json:"password" validate:"required,min=8,max=255"`
 }
 
+// dummyPasswordHash is a bcrypt hash at the default cost. Logins for unknown
+// emails are checked against it so they take as long as a wrong password.
+const dummyPasswordHash = "$2a$10$QPMNMOUjXusH93D2H0wFfOydC9nunSBsppEUOum5bhKiXDxImBzhO"
+
 func (api *API) loginUserHandler(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := api.CreateContext()
 	defer cancel()
@@ -118,38 +162,55 @@ func (api *API) loginUserHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := api.models.Users.GetByEmail(ctx, req.Email)
+	attempt, wait, err := api.reserveLogin(ctx, req.Email, clientIP(r))
 	if err != nil {
+		api.internalServerErrorResponse(w, r, err)
+		return
+	}
+	if wait > 0 {
+		api.tooManyAttemptsResponse(w, wait)
+		return
+	}
+
+	user, err := api.models.Users.GetByEmail(ctx, req.Email)
+	if err != nil && !errors.Is(err, data.ErrRecordNotFound) {
 		api.handleDBError(w, r, err)
 		return
 	}
-	matches := utils.CheckPasswordHash(req.Password, user.Password)
-	if !matches {
-		api.badRequestResponse(w, err, "Invalid details provided")
+	// unknown emails fail exactly like wrong passwords, and just as slowly,
+	// so neither the response nor its timing reveals which accounts exist
+	passwordHash := dummyPasswordHash
+	if user != nil {
+		passwordHash = user.Password
+	}
+	if !utils.CheckPasswordHash(req.Password, passwordHash) || user == nil {
+		api.loginFailed(attempt, user)
+		api.badRequestResponse(w, errors.New("invalid credentials"), "Invalid details provided")
 		return
 	}
-	token, err := utils.GenerateToken(map[string]string{
-		"email":               user.Email,
-		"id":                  user.ID,
-		"first_name":          user.FirstName,
-		"last_name":           user.LastName,
-		"profile_picture_url": user.ProfilePicUrl,
-		"bio":                 user.Bio,
-	}, os.Getenv("JWT_SECRET"))
-	if err != nil {
-		api.internalServerErrorResponse(w, r, err)
+	api.loginPassed(ctx, attempt, !user.MFAEnabled)
+	if user.MFAEnabled {
+		mfaToken, err := api.keyring.Sign(map[string]string{
+			"id":  user.ID,
+			"typ": mfaPendingTokenType,
+		}, mfaPendingTokenTTL)
+		if err != nil {
+			api.internalServerErrorResponse(w, r, err)
+			return
+		}
+		api.writeSuccessResponse(w, http.StatusOK, envelope{
+			"mfa_required": true,
+			"mfa_token":    mfaToken,
+			"expires_in":   int(mfaPendingTokenTTL.Seconds()),
+		}, "Two-factor authentication required")
 		return
 	}
-	cookie := http.Cookie{
-		Name:     "access_token",
-		Value:    fmt.Sprintf("Bearer %s", token),
-		Expires:  time.Now().Add(15 * time.Minute),
-		HttpOnly: true,
-		Secure:   false,
-		Path:     "/",
+	tokens, err := api.startSession(ctx, w, r, user)
+	if err != nil {
+		api.handleDBError(w, r, err)
+		return
 	}
-	http.SetCookie(w, &cookie)
-	api.writeSuccessResponse(w, http.StatusOK, envelope{"token": token}, "Login successful")
+	api.writeSuccessResponse(w, http.StatusOK, tokens, "Login successful")
 }
 
 type GetUserDetailsRequest struct {
@@ -170,14 +231,23 @@ func (api *API) getUserDetailsHandler(w http.ResponseWriter, r *http.Request) {
 		api.handleDBError(w, r, err)
 		return
 	}
-	userDetails := map[string]string{
+	followerCount, followingCount, err := api.models.Followers.Counts(ctx, user.ID)
+	if err != nil {
+		api.handleDBError(w, r, err)
+		return
+	}
+	userDetails := map[string]any{
+		"username":            user.Username,
 		"first_name":          user.FirstName,
 		"last_name":           user.LastName,
 		"email":               user.Email,
 		"bio":                 user.Bio,
 		"profile_picture_url": user.ProfilePicUrl,
 		"created_at":          user.CreatedAt.UTC().String(),
+		"follower_count":      followerCount,
+		"following_count":     followingCount,
 	}
+	api.setETag(w, user.Version)
 	api.writeSuccessResponse(w, http.StatusOK, envelope{"user": userDetails}, "")
 }
 
@@ -186,8 +256,7 @@ type UpdateUserDetailsRequest struct {
 	LastName          *string `json:"last_name"`
 	Bio               *string `json:"bio"`
 	ProfilePictureUrl *string `json:"profile_picture_url"`
-	Activated         *bool   `json:"activated"`
-	ID                string  `json:"id" validate:"required"`
+	Version           *int    `json:"version"`
 }
 
 func (api *API) updateUserDetailsHandler(w http.ResponseWriter, r *http.Request) {
@@ -206,11 +275,7 @@ func (api *API) updateUserDetailsHandler(w http.ResponseWriter, r *http.Request)
 		api.failedValidationResponse(w, validationError)
 		return
 	}
-	user, err := api.models.Users.GetByID(ctx, req.ID)
-	if err != nil {
-		api.handleDBError(w, r, err)
-		return
-	}
+	user := api.contextGetUser(r)
 	if req.FirstName != nil {
 		user.FirstName = *req.FirstName
 	}
@@ -223,19 +288,22 @@ func (api *API) updateUserDetailsHandler(w http.ResponseWriter, r *http.Request)
 	if req.ProfilePictureUrl != nil {
 		user.ProfilePicUrl = *req.ProfilePictureUrl
 	}
-	if req.Activated != nil {
-		user.Activated = *req.Activated
+	var ifMatch bool
+	user.Version, ifMatch, err = api.expectedVersion(r, req.Version, user.Version)
+	if err != nil {
+		api.badRequestResponse(w, err, err.Error())
+		return
 	}
 	updateInfo, err := api.models.Users.UpdateDetails(ctx, user)
 	if err != nil {
-		api.handleDBError(w, r, err)
+		api.updateErrorResponse(w, r, err, ifMatch)
 		return
 	}
+	api.setETag(w, updateInfo.Version)
 	api.writeSuccessResponse(w, http.StatusOK, envelope{"data": updateInfo}, "User updated successfully")
 }
 
 type UpdateUserEmailRequest struct {
-	Email    string `json:"email" validate:"required,email"`
 	NewEmail string `json:"new_email" validate:"required,email"`
 	Password string `json:"password" validate:"required,min=8"`
 }
@@ -255,28 +323,41 @@ func (api *API) updateUserEmailHandler(w http.ResponseWriter, r *http.Request) {
 		api.failedValidationResponse(w, validationError)
 		return
 	}
-	user, err := api.models.Users.GetByEmail(ctx, req.Email)
-	if err != nil {
-		api.handleDBError(w, r, err)
-		return
-	}
+	user := api.contextGetUser(r)
 	matches := utils.CheckPasswordHash(req.Password, user.Password)
 	if !matches {
 		api.badRequestResponse(w, err, "Invalid details provided")
 		return
 	}
-	updateInfo, err := api.models.Users.UpdateEmail(ctx, req.Email, req.NewEmail)
+	existingUser, err := api.models.Users.GetByEmail(ctx, req.NewEmail)
+	if existingUser != nil {
+		api.conflictResponse(w, "User with email already exists")
+		return
+	}
+	if err != nil && !errors.Is(err, data.ErrRecordNotFound) {
+		api.handleDBError(w, r, err)
+		return
+	}
+	// the address only changes once the link sent to it is followed, so
+	// nobody can move an account to an address they don't control
+	changeToken, err := api.models.EmailChanges.Create(ctx, user.ID, req.NewEmail, emailChangeTokenTTL)
 	if err != nil {
 		api.handleDBError(w, r, err)
 		return
 	}
-	api.writeSuccessResponse(w, http.StatusOK, envelope{"data": updateInfo}, "User email updated successfully")
+	api.sendEmail(req.NewEmail, "email_change.tmpl", map[string]any{
+		"firstName":  user.FirstName,
+		"oldEmail":   user.Email,
+		"newEmail":   req.NewEmail,
+		"confirmURL": api.emailChangeURL(changeToken.Token),
+		"expiresIn":  emailChangeTokenTTL.String(),
+	})
+	api.writeSuccessResponse(w, http.StatusAccepted, nil, "Check your new email address to confirm the change")
 }
 
 type UpdateUserPasswordRequest struct {
-	Email           string `json:"email" validate:"required,email"`
 	CurrentPassword string `json:"current_password" validate:"required"`
-	NewPassword     string `json:"new_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required,max=72"`
 }
 
 func (api *API) updateUserPasswordHandler(w http.ResponseWriter, r *http.Request) {
@@ -296,19 +377,27 @@ func (api *API) updateUserPasswordHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	user, err := api.models.Users.GetByEmail(ctx, req.Email)
-	if err != nil {
-		api.handleDBError(w, r, err)
-		return
-	}
+	user := api.contextGetUser(r)
 	matches := utils.CheckPasswordHash(req.CurrentPassword, user.Password)
 	if !matches {
 		api.badRequestResponse(w, err, "Invalid details provided")
 		return
 	}
+	if !api.checkNewPassword(ctx, w, r, req.NewPassword, user.FirstName, user.LastName, user.Username, user.Email) {
+		return
+	}
 
-	hashedPassword, _ := utils.HashPassword(req.NewPassword)
-	updateInfo, err := api.models.Users.UpdatePassword(ctx, req.Email, hashedPassword)
+	hashedPassword, err := utils.HashPassword(req.NewPassword)
+	if err != nil {
+		api.internalServerErrorResponse(w, r, err)
+		return
+	}
+	updateInfo, err := api.models.Users.UpdatePassword(ctx, user.Email, hashedPassword)
+	if err != nil {
+		api.handleDBError(w, r, err)
+		return
+	}
+	_, err = api.models.Sessions.RevokeAllForUser(ctx, user.ID, api.contextGetSessionID(r))
 	if err != nil {
 		api.handleDBError(w, r, err)
 		return
@@ -337,6 +426,16 @@ func (api *API) resetPasswordRequestHandler(w http.ResponseWriter, r *http.Reque
 		api.failedValidationResponse(w, validationError)
 		return
 	}
+	wait, err := api.reserveReset(ctx, req.Email, clientIP(r))
+	if err != nil {
+		api.internalServerErrorResponse(w, r, err)
+		return
+	}
+	if wait > 0 {
+		api.tooManyAttemptsResponse(w, wait)
+		return
+	}
+
 	user, err := api.models.Users.GetByEmail(ctx, req.Email)
 	if user == nil {
 		api.writeSuccessResponse(w, http.StatusOK, nil, "Token has been sent to your email if you have an account")
@@ -371,12 +470,18 @@ func (api *API) resetPasswordRequestHandler(w http.ResponseWriter, r *http.Reque
 			return
 		}
 	}
-	api.writeSuccessResponse(w, http.StatusOK, envelope{"reset_token": resetToken}, "Token has been sent to your email if you have an account")
+	api.sendEmail(user.Email, "password_reset.tmpl", map[string]any{
+		"firstName":  user.FirstName,
+		"resetToken": resetToken,
+		"resetURL":   fmt.Sprintf("%s/reset-password?token=%s", api.env.ClientUrl, url.QueryEscape(resetToken)),
+		"expiresIn":  time.Until(expiration).Round(time.Minute).String(),
+	})
+	api.writeSuccessResponse(w, http.StatusOK, nil, "Token has been sent to your email if you have an account")
 }
 
 type ResetPasswordFormRequest struct {
 	ResetToken  string `json:"reset_token" validate:"required"`
-	NewPassword string `json:"new_password" validate:"required"`
+	NewPassword string `json:"new_password" validate:"required,max=72"`
 }
 
 func (api *API) resetPasswordHandler(w http.ResponseWriter, r *http.Request) {
@@ -396,7 +501,10 @@ func (api *API) resetPasswordHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	existingResetToken, err := api.models.ResetTokens.GetByToken(ctx, req.ResetToken)
-
+	if err != nil {
+		api.handleDBError(w, r, err)
+		return
+	}
 	if existingResetToken.Expiration.UTC().Before(time.Now().UTC()) {
 		_, err = api.models.ResetTokens.DeleteByToken(ctx, req.ResetToken)
 		if err != nil {
@@ -406,17 +514,19 @@ func (api *API) resetPasswordHandler(w http.ResponseWriter, r *http.Request) {
 		api.writeErrorResponse(w, http.StatusGone, ErrExpired, "Password reset token has expired", nil)
 		return
 	}
-
+	user, err := api.models.Users.GetByID(ctx, existingResetToken.UserID)
 	if err != nil {
 		api.handleDBError(w, r, err)
 		return
 	}
-	user, err := api.models.Users.GetByID(ctx, existingResetToken.UserID)
-	if err != nil {
-		api.handleDBError(w, r, err)
+	if !api.checkNewPassword(ctx, w, r, req.NewPassword, user.FirstName, user.LastName, user.Username, user.Email) {
 		return
 	}
 	hashedPassword, err := utils.HashPassword(req.NewPassword)
+	if err != nil {
+		api.internalServerErrorResponse(w, r, err)
+		return
+	}
 	updateInfo, err := api.models.Users.UpdatePassword(ctx, user.Email, hashedPassword)
 	if err != nil {
 		api.handleDBError(w, r, err)
@@ -427,41 +537,42 @@ func (api *API) resetPasswordHandler(w http.ResponseWriter, r *http.Request) {
 		api.handleDBError(w, r, err)
 		return
 	}
+	_, err = api.models.Sessions.RevokeAllForUser(ctx, user.ID, "")
+	if err != nil {
+		api.handleDBError(w, r, err)
+		return
+	}
 	api.writeSuccessResponse(w, http.StatusOK, envelope{"data": updateInfo}, "User password reset successfully")
 }
 
-type FollowerRequest struct {
-	FollowerID string `json:"follower_id" validate:"required"`
-	FollowedID string `json:"followed_id" validate:"required"`
-}
-
 func (api *API) followUserHandler(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := api.CreateContext()
 	defer cancel()
 
-	var req FollowerRequest
-
-	err := api.readJSON(w, r, &req)
+	followedID, err := api.readParam(r, "id")
 	if err != nil {
-		api.badRequestResponse(w, err, err.Error())
+		api.badRequestResponse(w, err, "ID parameter not provided")
 		return
 	}
-
-	v := validator.New()
-	if validationError := v.Struct(req); validationError != nil {
-		api.failedValidationResponse(w, validationError)
+	follower := api.contextGetUser(r)
+	if followedID == follower.ID {
+		api.badRequestResponse(w, errors.New("you cannot follow yourself"), "You cannot follow yourself")
 		return
 	}
-
-	if req.FollowerID == req.FollowedID {
-		api.badRequestResponse(w, errors.New("you cannot follow yourself"), "You cannot follow yourself")
+	if _, err = api.models.Users.GetByID(ctx, followedID); err != nil {
+		api.handleDBError(w, r, err)
 		return
 	}
-	_, err = api.models.Followers.FollowUser(ctx, req.FollowerID, req.FollowedID)
+	_, err = api.models.Followers.FollowUser(ctx, follower.ID, followedID)
 	if err != nil {
 		api.handleDBError(w, r, err)
 		return
 	}
+	api.createNotification(ctx, &data.Notification{
+		RecipientID: followedID,
+		ActorID:     follower.ID,
+		Type:        data.NotificationFollow,
+	})
 	api.writeSuccessResponse(w, http.StatusCreated, nil, "User followed successfully")
 }
 
@@ -469,24 +580,78 @@ func (api *API) unfollowUserHandler(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := api.CreateContext()
 	defer cancel()
 
-	var req FollowerRequest
-	err := api.readJSON(w, r, &req)
+	followedID, err := api.readParam(r, "id")
+	if err != nil {
+		api.badRequestResponse(w, err, "ID parameter not provided")
+		return
+	}
+	err = api.models.Followers.UnfollowUser(ctx, api.contextGetUser(r).ID, followedID)
+	if err != nil {
+		api.handleDBError(w, r, err)
+		return
+	}
+	api.writeSuccessResponse(w, http.StatusOK, nil, "User unfollowed successfully")
+
+}
+
+func (api *API) listFollowersHandler(w http.ResponseWriter, r *http.Request) {
+	api.listFollows(w, r, api.models.Followers.ListFollowers, "followers")
+}
+
+func (api *API) listFollowingHandler(w http.ResponseWriter, r *http.Request) {
+	api.listFollows(w, r, api.models.Followers.ListUsersFollowed, "following")
+}
+
+type followListFunc func(ctx context.Context, userID, viewerID string, filters data.Filters) ([]*data.FollowListEntry, data.Metadata, error)
+
+// listFollows pages through one side of the user's follow graph. The id
+// parameter may be "me".
+func (api *API) listFollows(w http.ResponseWriter, r *http.Request, list followListFunc, key string) {
+	ctx, cancel := api.CreateContext()
+	defer cancel()
+
+	id, err := api.readParam(r, "id")
+	if err != nil {
+		api.badRequestResponse(w, err, "ID parameter not provided")
+		return
+	}
+	viewer := api.contextGetUser(r)
+	if id == "me" {
+		id = viewer.ID
+	}
+
+	// the lists are always newest follow first, so only the page is read
+	qs := r.URL.Query()
+	page, err := api.readInt(qs, "page", 1)
 	if err != nil {
 		api.badRequestResponse(w, err, err.Error())
 		return
 	}
-	v := validator.New()
-	if validationError := v.Struct(req); validationError != nil {
-		api.failedValidationResponse(w, validationError)
+	if page < 1 || page > 10_000_000 {
+		api.badRequestResponse(w, errors.New("page out of range"), "page must be between 1 and 10000000")
 		return
 	}
-	err = api.models.Followers.UnfollowUser(ctx, req.FollowerID, req.FollowedID)
+	pageSize, err := api.readInt(qs, "page_size", 20)
 	if err != nil {
-		api.handleDBError(w, r, err)
+		api.badRequestResponse(w, err, err.Error())
+		return
+	}
+	if pageSize < 1 || pageSize > 100 {
+		api.badRequestResponse(w, errors.New("page_size out of range"), "page_size must be between 1 and 100")
 		return
 	}
-	api.writeSuccessResponse(w, http.StatusCreated, nil, "User unfollowed successfully")
+	filters := data.Filters{Page: page, PageSize: pageSize}
 
+	if _, err = api.models.Users.GetByID(ctx, id); err != nil {
+		api.handleDBError(w, r, err)
+		return
+	}
+	users, metadata, err := list(ctx, id, viewer.ID, filters)
+	if err != nil {
+		api.handleDBError(w, r, err)
+		return
+	}
+	api.successResponseWithPagination(w, http.StatusOK, envelope{key: users}, metadata, "")
 }
 
 type DeleteUserRequest struct {
@@ -511,8 +676,7 @@ func (api *API) deleteUserAccountHandler(w http.ResponseWriter, r *http.Request)
 	}
 
 	user := api.contextGetUser(r)
-	userDetails, _ := api.models.Users.GetByID(ctx, user.ID)
-	matches := utils.CheckPasswordHash(req.Password, userDetails.Password)
+	matches := utils.CheckPasswordHash(req.Password, user.Password)
 	if !matches {
 		api.badRequestResponse(w, err, "Invalid details provided")
 		return
@@ -0,0 +1,72 @@
+// Package trending ranks articles by recent engagement, decayed by age the
+// way Hacker News ranks stories.
+package trending
+
+import (
+	"math"
+	"time"
+)
+
+// Engagement is what an article received within a window.
+type Engagement struct {
+	Likes    int
+	Comments int
+	Saves    int
+	Views    int
+}
+
+// Params weigh each kind of engagement and set how fast scores decay.
+type Params struct {
+	LikeWeight    float64
+	CommentWeight float64
+	SaveWeight    float64
+	ViewWeight    float64
+	// Gravity is the exponent age is raised to. Higher values let older
+	// articles fall away faster.
+	Gravity float64
+}
+
+// DefaultParams count a comment or a save for more than a like, since they
+// take more effort, and a view for much less. Gravity is Hacker News's.
+var DefaultParams = Params{
+	LikeWeight:    1,
+	CommentWeight: 2,
+	SaveWeight:    3,
+	ViewWeight:    0.05,
+	Gravity:       1.8,
+}
+
+// Score is points / (hours + 2)^gravity, where points is the weighted sum of
+// the engagement and hours is the article's age. The two hours of headroom
+// stop brand new articles from scoring arbitrarily high.
+func Score(e Engagement, age time.Duration, p Params) float64 {
+	points := p.LikeWeight*float64(e.Likes) +
+		p.CommentWeight*float64(e.Comments) +
+		p.SaveWeight*float64(e.Saves) +
+		p.ViewWeight*float64(e.Views)
+	if points <= 0 {
+		return 0
+	}
+	hours := math.Max(age.Hours(), 0)
+	return points / math.Pow(hours+2, p.Gravity)
+}
+
+// Window is a span of recent engagement articles are ranked on.
+type Window struct {
+	Name     string
+	Duration time.Duration
+}
+
+var Windows = []Window{
+	{Name: "24h", Duration: 24 * time.Hour},
+	{Name: "7d", Duration: 7 * 24 * time.Hour},
+}
+
+func ParseWindow(name string) (Window, bool) {
+	for _, window := range Windows {
+		if window.Name == name {
+			return window, true
+		}
+	}
+	return Window{}, false
+}
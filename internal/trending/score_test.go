@@ -0,0 +1,140 @@
+package trending
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestScore(t *testing.T) {
+	tests := []struct {
+		name       string
+		engagement Engagement
+		age        time.Duration
+		params     Params
+		want       float64
+	}{
+		{
+			name:   "no engagement",
+			age:    time.Hour,
+			params: DefaultParams,
+			want:   0,
+		},
+		{
+			name:       "brand new",
+			engagement: Engagement{Likes: 4},
+			params:     DefaultParams,
+			want:       4 / math.Pow(2, 1.8),
+		},
+		{
+			name:       "a day old",
+			engagement: Engagement{Likes: 4},
+			age:        24 * time.Hour,
+			params:     DefaultParams,
+			want:       4 / math.Pow(26, 1.8),
+		},
+		{
+			name:       "weighted engagement",
+			engagement: Engagement{Likes: 1, Comments: 1, Saves: 1, Views: 20},
+			age:        2 * time.Hour,
+			params:     DefaultParams,
+			want:       (1 + 2 + 3 + 1) / math.Pow(4, 1.8),
+		},
+		{
+			name:       "published in the future counts as new",
+			engagement: Engagement{Likes: 4},
+			age:        -time.Hour,
+			params:     DefaultParams,
+			want:       4 / math.Pow(2, 1.8),
+		},
+		{
+			name:       "no gravity",
+			engagement: Engagement{Comments: 3},
+			age:        100 * time.Hour,
+			params:     Params{CommentWeight: 1},
+			want:       3,
+		},
+		{
+			name:       "negative weights never score below zero",
+			engagement: Engagement{Likes: 5},
+			params:     Params{LikeWeight: -1, Gravity: 1.8},
+			want:       0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Score(tt.engagement, tt.age, tt.params)
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("Score() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScoreOrdering(t *testing.T) {
+	tests := []struct {
+		name          string
+		higher, lower Engagement
+		higherAge     time.Duration
+		lowerAge      time.Duration
+	}{
+		{
+			name:      "more likes rank higher",
+			higher:    Engagement{Likes: 10},
+			lower:     Engagement{Likes: 5},
+			higherAge: 3 * time.Hour,
+			lowerAge:  3 * time.Hour,
+		},
+		{
+			name:      "newer ranks higher at equal engagement",
+			higher:    Engagement{Likes: 10},
+			lower:     Engagement{Likes: 10},
+			higherAge: time.Hour,
+			lowerAge:  10 * time.Hour,
+		},
+		{
+			name:      "a comment outweighs a like",
+			higher:    Engagement{Comments: 1},
+			lower:     Engagement{Likes: 1},
+			higherAge: time.Hour,
+			lowerAge:  time.Hour,
+		},
+		{
+			name:      "a fresh article overtakes a popular old one",
+			higher:    Engagement{Likes: 20},
+			lower:     Engagement{Likes: 200},
+			higherAge: 2 * time.Hour,
+			lowerAge:  72 * time.Hour,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			higher := Score(tt.higher, tt.higherAge, DefaultParams)
+			lower := Score(tt.lower, tt.lowerAge, DefaultParams)
+			if higher <= lower {
+				t.Errorf("expected %v to outscore %v", higher, lower)
+			}
+		})
+	}
+}
+
+func TestParseWindow(t *testing.T) {
+	tests := []struct {
+		name   string
+		want   time.Duration
+		wantOK bool
+	}{
+		{name: "24h", want: 24 * time.Hour, wantOK: true},
+		{name: "7d", want: 7 * 24 * time.Hour, wantOK: true},
+		{name: "30d", wantOK: false},
+		{name: "", wantOK: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			window, ok := ParseWindow(tt.name)
+			if ok != tt.wantOK || window.Duration != tt.want {
+				t.Errorf("ParseWindow(%q) = %v, %v; want %v, %v", tt.name, window.Duration, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+)
+
+type DiffOp string
+
+const (
+	DiffEqual  DiffOp = "equal"
+	DiffInsert DiffOp = "insert"
+	DiffDelete DiffOp = "delete"
+)
+
+type DiffLine struct {
+	Op   DiffOp `json:"op"`
+	Text string `json:"text"`
+}
+
+// MaxDiffCells bounds the table DiffLines builds, which holds one entry per
+// pair of changed lines: 4M entries is about 32MB.
+const MaxDiffCells = 1 << 22
+
+var ErrDiffTooLarge = errors.New("too many changed lines to diff")
+
+// DiffLines returns a line-level diff that turns a into b, computed from the
+// longest common subsequence of their lines. Lines shared at the start and
+// end are set aside first, so only the changed middle costs memory; if that
+// is still over MaxDiffCells it returns ErrDiffTooLarge.
+func DiffLines(a, b string) ([]DiffLine, error) {
+	from := strings.Split(a, "\n")
+	to := strings.Split(b, "\n")
+
+	prefix := 0
+	for prefix < len(from) && prefix < len(to) && from[prefix] == to[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(from)-prefix && suffix < len(to)-prefix && from[len(from)-1-suffix] == to[len(to)-1-suffix] {
+		suffix++
+	}
+	if (len(from)-prefix-suffix+1)*(len(to)-prefix-suffix+1) > MaxDiffCells {
+		return nil, ErrDiffTooLarge
+	}
+
+	var diff []DiffLine
+	for _, line := range from[:prefix] {
+		diff = append(diff, DiffLine{Op: DiffEqual, Text: line})
+	}
+	common := from[len(from)-suffix:]
+	from = from[prefix : len(from)-suffix]
+	to = to[prefix : len(to)-suffix]
+
+	// lcs[i][j] holds the LCS length of from[i:] and to[j:]
+	lcs := make([][]int, len(from)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(to)+1)
+	}
+	for i := len(from) - 1; i >= 0; i-- {
+		for j := len(to) - 1; j >= 0; j-- {
+			if from[i] == to[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(from) && j < len(to) {
+		switch {
+		case from[i] == to[j]:
+			diff = append(diff, DiffLine{Op: DiffEqual, Text: from[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			diff = append(diff, DiffLine{Op: DiffDelete, Text: from[i]})
+			i++
+		default:
+			diff = append(diff, DiffLine{Op: DiffInsert, Text: to[j]})
+			j++
+		}
+	}
+	for ; i < len(from); i++ {
+		diff = append(diff, DiffLine{Op: DiffDelete, Text: from[i]})
+	}
+	for ; j < len(to); j++ {
+		diff = append(diff, DiffLine{Op: DiffInsert, Text: to[j]})
+	}
+	for _, line := range common {
+		diff = append(diff, DiffLine{Op: DiffEqual, Text: line})
+	}
+	return diff, nil
+}
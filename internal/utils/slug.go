@@ -0,0 +1,65 @@
+package utils
+
+import (
+	"github.com/lucsky/cuid"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+	"strings"
+	"unicode"
+)
+
+const maxSlugLength = 80
+
+// letters that do not decompose into a base letter plus combining marks
+var slugFolds = strings.NewReplacer(
+	"ß", "ss", "æ", "ae", "Æ", "ae", "œ", "oe", "Œ", "oe",
+	"ø", "o", "Ø", "o", "đ", "d", "Đ", "d", "ł", "l", "Ł", "l", "þ", "th", "Þ", "th",
+)
+
+// Slugify turns a title into a lowercase, hyphen-separated URL segment.
+// Accents are folded to their base letters ("Café" becomes "cafe"), and
+// letters from scripts without an ASCII equivalent are kept as they are.
+func Slugify(title string) string {
+	fold := transform.Chain(norm.NFKD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	folded, _, err := transform.String(fold, slugFolds.Replace(title))
+	if err != nil {
+		folded = title
+	}
+
+	var b strings.Builder
+	pendingHyphen := false
+	length := 0
+	for _, r := range strings.ToLower(folded) {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			pendingHyphen = length > 0
+			continue
+		}
+		if length >= maxSlugLength {
+			break
+		}
+		if pendingHyphen {
+			b.WriteByte('-')
+			length++
+			pendingHyphen = false
+		}
+		b.WriteRune(r)
+		length++
+	}
+	return strings.Trim(b.String(), "-")
+}
+
+// GenerateUsername derives a username for accounts registered without one
+// from the ASCII letters and digits of their name plus a short random suffix.
+func GenerateUsername(firstName, lastName string) string {
+	var b strings.Builder
+	for _, r := range Slugify(firstName + lastName) {
+		if b.Len() >= 20 {
+			break
+		}
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		}
+	}
+	return b.String() + strings.ToLower(cuid.Slug())
+}
@@ -1,48 +1,35 @@
 package utils
 
 import (
-	"fmt"
-	"github.com/golang-jwt/jwt/v5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
 	"github.com/lucsky/cuid"
 	"strings"
 	"time"
 )
 
-func GenerateToken(payload map[string]string, secret string) (string, error) {
-	expirationTime := time.Now().Add(time.Hour * 1500).Unix()
-	claims := jwt.MapClaims{
-		"exp": expirationTime,
-	}
-	for key, value := range payload {
-		claims[key] = value
-	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(secret))
-	if err != nil {
-		return "", err
-	}
-	return tokenString, nil
-}
-
-func DecodeToken[T jwt.Claims](tokenString string, secret string, claims T) (T, error) {
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (any, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(secret), nil
-	})
-	if err != nil {
-		return claims, err
-	}
-	if _, ok := token.Claims.(T); ok && token.Valid {
-		return claims, nil
-	}
-	return claims, fmt.Errorf("invalid token provided")
-}
-
 func GenerateResetToken() (string, time.Time) {
 	parts := []string{cuid.New(), cuid.New()}
 	token := strings.Join(parts, "")[:32]
 	expiry := time.Now().Add(time.Minute * 15)
 	return token, expiry
 }
+
+// GenerateSecureToken returns a random, URL-safe token together with the
+// SHA-256 hash that should be stored in its place.
+func GenerateSecureToken() (string, string, error) {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b)
+	return token, HashToken(token), nil
+}
+
+// HashToken is the lookup key for a token generated by GenerateSecureToken.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
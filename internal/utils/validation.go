@@ -4,6 +4,8 @@ import (
 	"errors"
 	"fmt"
 	"github.com/go-playground/validator/v10"
+	"reflect"
+	"time"
 )
 
 func getErrorMessage(err validator.FieldError) string {
@@ -13,14 +15,56 @@ func getErrorMessage(err validator.FieldError) string {
 	case "email":
 		return "Invalid email address"
 	case "min":
+		if isNumeric(err.Kind()) {
+			return fmt.Sprintf("%s must be at least %s", err.Field(), err.Param())
+		}
 		return fmt.Sprintf("%s must be at least %s characters", err.Field(), err.Param())
+	case "max":
+		if isNumeric(err.Kind()) {
+			return fmt.Sprintf("%s must be at most %s", err.Field(), err.Param())
+		}
+		return fmt.Sprintf("%s must be at most %s characters", err.Field(), err.Param())
+	case "gt":
+		if err.Type() == reflect.TypeOf(time.Time{}) {
+			return fmt.Sprintf("%s must be in the future", err.Field())
+		}
+		return fmt.Sprintf("%s must be greater than %s", err.Field(), err.Param())
+	case "required_without":
+		return fmt.Sprintf("%s is required when %s is not provided", err.Field(), err.Param())
+	case "len":
+		return fmt.Sprintf("%s must be exactly %s characters", err.Field(), err.Param())
+	case "numeric":
+		return fmt.Sprintf("%s must contain only digits", err.Field())
+	case "oneof":
+		return fmt.Sprintf("%s must be one of: %s", err.Field(), err.Param())
 	default:
 		return fmt.Sprintf("%s is not valid", err.Field())
 	}
 }
 
+func isNumeric(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// messagesError is an error from a check the validator can't express, such
+// as the password policy, that carries its own messages.
+type messagesError interface {
+	error
+	Messages() []string
+}
+
 func GetValidationErrors(err error) []string {
 	var errorMessages []string
+	var messages messagesError
+	if errors.As(err, &messages) {
+		return messages.Messages()
+	}
 	var validationErrors validator.ValidationErrors
 	errors.As(err, &validationErrors)
 
@@ -0,0 +1,162 @@
+// Package views counts article views and reading progress without writing
+// to the database on every request.
+package views
+
+import (
+	"context"
+	"github.com/rx-rz/65ch/internal/data"
+	"github.com/rx-rz/65ch/internal/jsonlog"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// DedupeWindow is how long a viewer's repeat visits count as one view.
+	DedupeWindow = 30 * time.Minute
+
+	defaultFlushInterval = 10 * time.Second
+	// defaultMaxBatch flushes early when traffic is heavy enough to fill a
+	// batch before the interval is up.
+	defaultMaxBatch = 1000
+)
+
+// Store is where flushed batches go; data.ArticleStatisticsModel in
+// production.
+type Store interface {
+	RecordViews(ctx context.Context, views []data.ArticleView, dedupeWindow time.Duration) error
+	RecordBeacons(ctx context.Context, beacons []data.ReadBeacon) error
+}
+
+type beaconKey struct {
+	articleID string
+	viewerKey string
+}
+
+// Recorder buffers views and beacons in memory and writes them in batches.
+// It drops repeat views from a viewer it has seen recently itself; the
+// store catches the ones other replicas saw.
+type Recorder struct {
+	store         Store
+	logger        *jsonlog.Logger
+	flushInterval time.Duration
+	maxBatch      int
+	now           func() time.Time
+
+	mu      sync.Mutex
+	seen    map[beaconKey]time.Time
+	views   []data.ArticleView
+	beacons map[beaconKey]data.ReadBeacon
+	full    chan struct{}
+}
+
+func NewRecorder(store Store, logger *jsonlog.Logger) *Recorder {
+	return &Recorder{
+		store:         store,
+		logger:        logger,
+		flushInterval: defaultFlushInterval,
+		maxBatch:      defaultMaxBatch,
+		now:           time.Now,
+		seen:          make(map[beaconKey]time.Time),
+		beacons:       make(map[beaconKey]data.ReadBeacon),
+		full:          make(chan struct{}, 1),
+	}
+}
+
+// View records that viewerKey opened the article, and reports whether it
+// counted as a new view.
+func (r *Recorder) View(articleID, viewerKey string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	key := beaconKey{articleID: articleID, viewerKey: viewerKey}
+	if last, ok := r.seen[key]; ok && now.Sub(last) < DedupeWindow {
+		return false
+	}
+	r.seen[key] = now
+	r.views = append(r.views, data.ArticleView{ArticleID: articleID, ViewerKey: viewerKey, ViewedAt: now})
+	if len(r.views) >= r.maxBatch {
+		r.signalFull()
+	}
+	return true
+}
+
+// Beacon records reading progress. Beacons from the same viewer between
+// flushes are merged, keeping the deepest scroll and the total time.
+func (r *Recorder) Beacon(beacon data.ReadBeacon) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := beaconKey{articleID: beacon.ArticleID, viewerKey: beacon.ViewerKey}
+	if existing, ok := r.beacons[key]; ok {
+		beacon.ReadDepth = max(beacon.ReadDepth, existing.ReadDepth)
+		beacon.Seconds += existing.Seconds
+	}
+	r.beacons[key] = beacon
+	if len(r.beacons) >= r.maxBatch {
+		r.signalFull()
+	}
+}
+
+func (r *Recorder) signalFull() {
+	select {
+	case r.full <- struct{}{}:
+	default:
+	}
+}
+
+// Run flushes every interval, or sooner when a batch fills up, until ctx is
+// cancelled. It flushes one last time on the way out.
+func (r *Recorder) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			r.Flush(flushCtx)
+			cancel()
+			return
+		case <-ticker.C:
+			r.Flush(ctx)
+		case <-r.full:
+			r.Flush(ctx)
+		}
+	}
+}
+
+// Flush writes everything buffered so far. Views go first, so beacons from
+// the same batch find the views they belong to. A failed batch is logged
+// and dropped rather than retried, since view counts don't need to be
+// exact.
+func (r *Recorder) Flush(ctx context.Context) {
+	r.mu.Lock()
+	views := r.views
+	r.views = nil
+	beacons := make([]data.ReadBeacon, 0, len(r.beacons))
+	for _, beacon := range r.beacons {
+		beacons = append(beacons, beacon)
+	}
+	r.beacons = make(map[beaconKey]data.ReadBeacon)
+	r.sweep()
+	r.mu.Unlock()
+
+	if err := r.store.RecordViews(ctx, views, DedupeWindow); err != nil {
+		r.logger.PrintError(err, map[string]string{"worker": "views", "dropped_views": strconv.Itoa(len(views))})
+	}
+	if err := r.store.RecordBeacons(ctx, beacons); err != nil {
+		r.logger.PrintError(err, map[string]string{"worker": "views", "dropped_beacons": strconv.Itoa(len(beacons))})
+	}
+}
+
+// sweep forgets viewers whose dedupe window has passed. r.mu must be held.
+func (r *Recorder) sweep() {
+	now := r.now()
+	for key, last := range r.seen {
+		if now.Sub(last) >= DedupeWindow {
+			delete(r.seen, key)
+		}
+	}
+}
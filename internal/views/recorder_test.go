@@ -0,0 +1,224 @@
+package views
+
+import (
+	"context"
+	"github.com/rx-rz/65ch/internal/data"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeClock struct{ now time.Time }
+
+func (c *fakeClock) Now() time.Time          { return c.now }
+func (c *fakeClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+// fakeStore keeps every non-empty batch it is given, and signals flushed
+// once the views of a flush have been recorded.
+type fakeStore struct {
+	mu      sync.Mutex
+	views   []data.ArticleView
+	beacons []data.ReadBeacon
+	// ctxErrs is the error of each flush's context at the time of writing.
+	ctxErrs []error
+	flushed chan struct{}
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{flushed: make(chan struct{}, 16)}
+}
+
+func (s *fakeStore) RecordViews(ctx context.Context, views []data.ArticleView, dedupeWindow time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(views) == 0 {
+		return nil
+	}
+	s.views = append(s.views, views...)
+	s.ctxErrs = append(s.ctxErrs, ctx.Err())
+	s.flushed <- struct{}{}
+	return nil
+}
+
+func (s *fakeStore) RecordBeacons(ctx context.Context, beacons []data.ReadBeacon) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.beacons = append(s.beacons, beacons...)
+	return nil
+}
+
+func (s *fakeStore) recorded() ([]data.ArticleView, []data.ReadBeacon, []error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.views, s.beacons, s.ctxErrs
+}
+
+func newTestRecorder(store Store, clock *fakeClock) *Recorder {
+	r := NewRecorder(store, nil)
+	r.now = clock.Now
+	// only the tests decide when a flush happens
+	r.flushInterval = time.Hour
+	return r
+}
+
+func waitForFlush(t *testing.T, store *fakeStore) {
+	t.Helper()
+	select {
+	case <-store.flushed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a flush")
+	}
+}
+
+func TestRecorderViewDedupe(t *testing.T) {
+	tests := []struct {
+		name      string
+		after     time.Duration
+		viewerKey string
+		wantNew   bool
+	}{
+		{"immediate repeat", 0, "viewer", false},
+		{"repeat inside the window", DedupeWindow - time.Second, "viewer", false},
+		{"repeat once the window passed", DedupeWindow, "viewer", true},
+		{"repeat well after the window", 2 * DedupeWindow, "viewer", true},
+		{"another viewer", 0, "other", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := &fakeClock{now: time.Date(2024, 11, 1, 12, 0, 0, 0, time.UTC)}
+			r := newTestRecorder(newFakeStore(), clock)
+			if !r.View("article", "viewer") {
+				t.Fatal("first view was not counted")
+			}
+			clock.Advance(tt.after)
+			if got := r.View("article", tt.viewerKey); got != tt.wantNew {
+				t.Errorf("got new view %v, want %v", got, tt.wantNew)
+			}
+		})
+	}
+}
+
+func TestRecorderDedupeSurvivesFlush(t *testing.T) {
+	ctx := context.Background()
+	clock := &fakeClock{now: time.Date(2024, 11, 1, 12, 0, 0, 0, time.UTC)}
+	store := newFakeStore()
+	r := newTestRecorder(store, clock)
+
+	r.View("article", "viewer")
+	clock.Advance(DedupeWindow / 2)
+	r.Flush(ctx)
+	if r.View("article", "viewer") {
+		t.Error("repeat view inside the window counted after a flush")
+	}
+	clock.Advance(DedupeWindow / 2)
+	r.Flush(ctx)
+	if !r.View("article", "viewer") {
+		t.Error("repeat view after the window was not counted")
+	}
+	r.Flush(ctx)
+
+	views, _, _ := store.recorded()
+	if len(views) != 2 {
+		t.Fatalf("store got %d views, want 2", len(views))
+	}
+	if want := clock.Now(); !views[1].ViewedAt.Equal(want) {
+		t.Errorf("second view stamped %v, want %v", views[1].ViewedAt, want)
+	}
+}
+
+func TestRecorderBeaconMerge(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 11, 1, 12, 0, 0, 0, time.UTC)}
+	store := newFakeStore()
+	r := newTestRecorder(store, clock)
+
+	for _, beacon := range []data.ReadBeacon{
+		{ArticleID: "a", ViewerKey: "viewer", ReadDepth: 40, Seconds: 10},
+		{ArticleID: "a", ViewerKey: "viewer", ReadDepth: 80, Seconds: 15},
+		{ArticleID: "a", ViewerKey: "viewer", ReadDepth: 60, Seconds: 5},
+		{ArticleID: "a", ViewerKey: "other", ReadDepth: 20, Seconds: 3},
+		{ArticleID: "b", ViewerKey: "viewer", ReadDepth: 100, Seconds: 30},
+	} {
+		r.Beacon(beacon)
+	}
+	r.Flush(context.Background())
+
+	_, beacons, _ := store.recorded()
+	sort.Slice(beacons, func(i, j int) bool {
+		if beacons[i].ArticleID != beacons[j].ArticleID {
+			return beacons[i].ArticleID < beacons[j].ArticleID
+		}
+		return beacons[i].ViewerKey < beacons[j].ViewerKey
+	})
+	want := []data.ReadBeacon{
+		{ArticleID: "a", ViewerKey: "other", ReadDepth: 20, Seconds: 3},
+		{ArticleID: "a", ViewerKey: "viewer", ReadDepth: 80, Seconds: 30},
+		{ArticleID: "b", ViewerKey: "viewer", ReadDepth: 100, Seconds: 30},
+	}
+	if len(beacons) != len(want) {
+		t.Fatalf("store got %d beacons, want %d: %+v", len(beacons), len(want), beacons)
+	}
+	for i := range want {
+		if beacons[i] != want[i] {
+			t.Errorf("beacon %d: got %+v, want %+v", i, beacons[i], want[i])
+		}
+	}
+}
+
+func TestRecorderRunFlushesFullBatch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	clock := &fakeClock{now: time.Date(2024, 11, 1, 12, 0, 0, 0, time.UTC)}
+	store := newFakeStore()
+	r := newTestRecorder(store, clock)
+	r.maxBatch = 3
+
+	done := make(chan struct{})
+	go func() {
+		r.Run(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	for _, viewer := range []string{"ada", "grace", "linus"} {
+		r.View("article", viewer)
+	}
+	// the interval is an hour, so only the full batch can trigger this
+	waitForFlush(t, store)
+	if views, _, _ := store.recorded(); len(views) != 3 {
+		t.Errorf("store got %d views, want 3", len(views))
+	}
+}
+
+func TestRecorderRunFlushesOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	clock := &fakeClock{now: time.Date(2024, 11, 1, 12, 0, 0, 0, time.UTC)}
+	store := newFakeStore()
+	r := newTestRecorder(store, clock)
+
+	done := make(chan struct{})
+	go func() {
+		r.Run(ctx)
+		close(done)
+	}()
+	r.View("article", "viewer")
+	r.Beacon(data.ReadBeacon{ArticleID: "article", ViewerKey: "viewer", ReadDepth: 50, Seconds: 12})
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after its context was cancelled")
+	}
+	views, beacons, ctxErrs := store.recorded()
+	if len(views) != 1 || len(beacons) != 1 {
+		t.Fatalf("final flush wrote %d views and %d beacons, want 1 of each", len(views), len(beacons))
+	}
+	// the last flush must not reuse the cancelled context, or the store
+	// would refuse the write
+	if ctxErrs[0] != nil {
+		t.Errorf("final flush ran with a done context: %v", ctxErrs[0])
+	}
+}
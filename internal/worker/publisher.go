@@ -0,0 +1,69 @@
+package worker
+
+import (
+	"context"
+	"github.com/rx-rz/65ch/internal/data"
+	"github.com/rx-rz/65ch/internal/jsonlog"
+	"strconv"
+	"time"
+)
+
+const defaultPublishBatchSize = 50
+
+// Publisher periodically moves scheduled articles whose publish time has
+// passed to published. Any number of API replicas can run one, since each
+// batch is claimed with FOR UPDATE SKIP LOCKED.
+type Publisher struct {
+	models    data.Models
+	logger    *jsonlog.Logger
+	interval  time.Duration
+	batchSize int
+}
+
+func NewPublisher(models data.Models, logger *jsonlog.Logger, interval time.Duration) *Publisher {
+	return &Publisher{
+		models:    models,
+		logger:    logger,
+		interval:  interval,
+		batchSize: defaultPublishBatchSize,
+	}
+}
+
+// Run polls until ctx is cancelled.
+func (p *Publisher) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	p.logger.PrintInfo("starting scheduled publisher", map[string]string{
+		"interval": p.interval.String(),
+	})
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.publishDue(ctx)
+		}
+	}
+}
+
+func (p *Publisher) publishDue(ctx context.Context) {
+	for {
+		queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		ids, err := p.models.Articles.PublishDue(queryCtx, p.batchSize)
+		cancel()
+		if err != nil {
+			p.logger.PrintError(err, map[string]string{"worker": "publisher"})
+			return
+		}
+		if len(ids) > 0 {
+			p.logger.PrintInfo("published scheduled articles", map[string]string{
+				"count": strconv.Itoa(len(ids)),
+			})
+		}
+		// a full batch means more articles may be due, so keep draining
+		if len(ids) < p.batchSize {
+			return
+		}
+	}
+}
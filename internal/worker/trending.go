@@ -0,0 +1,100 @@
+package worker
+
+import (
+	"context"
+	"github.com/rx-rz/65ch/internal/data"
+	"github.com/rx-rz/65ch/internal/jsonlog"
+	"github.com/rx-rz/65ch/internal/trending"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// defaultTrendingLimit is how many articles are kept per window. Nobody
+// scrolls further down a trending tab than this.
+const defaultTrendingLimit = 500
+
+// Trending periodically rescores articles for every trending window and
+// stores the top of each ranking for the API to read.
+type Trending struct {
+	models   data.Models
+	logger   *jsonlog.Logger
+	interval time.Duration
+	limit    int
+	params   trending.Params
+	now      func() time.Time
+}
+
+func NewTrending(models data.Models, logger *jsonlog.Logger, interval time.Duration) *Trending {
+	return &Trending{
+		models:   models,
+		logger:   logger,
+		interval: interval,
+		limit:    defaultTrendingLimit,
+		params:   trending.DefaultParams,
+		now:      time.Now,
+	}
+}
+
+// Run refreshes once straight away, so a restart doesn't leave the tab
+// stale for a whole interval, then polls until ctx is cancelled.
+func (t *Trending) Run(ctx context.Context) {
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+
+	t.logger.PrintInfo("starting trending scorer", map[string]string{
+		"interval": t.interval.String(),
+	})
+	t.refresh(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			t.refresh(ctx)
+		}
+	}
+}
+
+func (t *Trending) refresh(ctx context.Context) {
+	for _, window := range trending.Windows {
+		queryCtx, cancel := context.WithTimeout(ctx, time.Minute)
+		count, err := t.refreshWindow(queryCtx, window)
+		cancel()
+		if err != nil {
+			t.logger.PrintError(err, map[string]string{"worker": "trending", "window": window.Name})
+			continue
+		}
+		t.logger.PrintInfo("refreshed trending articles", map[string]string{
+			"window": window.Name,
+			"count":  strconv.Itoa(count),
+		})
+	}
+}
+
+func (t *Trending) refreshWindow(ctx context.Context, window trending.Window) (int, error) {
+	now := t.now()
+	engagement, err := t.models.Trending.Engagement(ctx, now.Add(-window.Duration))
+	if err != nil {
+		return 0, err
+	}
+	scores := make([]data.TrendingScore, 0, len(engagement))
+	for _, e := range engagement {
+		score := trending.Score(trending.Engagement{
+			Likes:    e.Likes,
+			Comments: e.Comments,
+			Saves:    e.Saves,
+			Views:    e.Views,
+		}, now.Sub(e.PublishedAt), t.params)
+		if score > 0 {
+			scores = append(scores, data.TrendingScore{ArticleEngagement: *e, Score: score})
+		}
+	}
+	sort.Slice(scores, func(i, j int) bool {
+		return scores[i].Score > scores[j].Score
+	})
+	if len(scores) > t.limit {
+		scores = scores[:t.limit]
+	}
+	return len(scores), t.models.Trending.Replace(ctx, window.Name, scores)
+}
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE article_revisions(
+    id serial primary key,
+    article_id uuid not null references articles(id) on delete cascade,
+    revision integer not null,
+    title text not null,
+    content text not null,
+    created_at timestamptz not null default now(),
+    CONSTRAINT unique_article_revision UNIQUE (article_id, revision)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE article_revisions;
+-- +goose StatementEnd
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE articles
+    DROP CONSTRAINT IF EXISTS articles_status_check;
+ALTER TABLE articles
+    ADD CONSTRAINT articles_status_check CHECK (status IN ('draft', 'scheduled', 'published'));
+CREATE INDEX articles_scheduled_publish_idx ON articles (published_at) WHERE status = 'scheduled';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS articles_scheduled_publish_idx;
+ALTER TABLE articles
+    DROP CONSTRAINT IF EXISTS articles_status_check;
+ALTER TABLE articles
+    ADD CONSTRAINT articles_status_check CHECK (status IN ('draft', 'published'));
+-- +goose StatementEnd
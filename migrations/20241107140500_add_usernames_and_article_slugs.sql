@@ -0,0 +1,37 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE users
+    ADD COLUMN username text;
+UPDATE users
+SET username = lower(regexp_replace(first_name || last_name, '[^a-zA-Z0-9]', '', 'g')) || substr(md5(id::text), 1, 7);
+ALTER TABLE users
+    ALTER COLUMN username SET NOT NULL,
+    ADD CONSTRAINT unique_username UNIQUE (username);
+
+ALTER TABLE articles
+    ADD COLUMN slug text;
+UPDATE articles
+SET slug = coalesce(nullif(trim(both '-' from lower(regexp_replace(title, '[^a-zA-Z0-9]+', '-', 'g'))), ''), 'untitled')
+    || '-' || substr(md5(id::text), 1, 7);
+ALTER TABLE articles
+    ALTER COLUMN slug SET NOT NULL,
+    ADD CONSTRAINT unique_author_slug UNIQUE (author_id, slug);
+
+CREATE TABLE article_slug_history(
+    id serial primary key,
+    article_id uuid not null references articles(id) on delete cascade,
+    author_id uuid not null references users(id) on delete cascade,
+    slug text not null,
+    created_at timestamptz not null default now(),
+    CONSTRAINT unique_author_slug_history UNIQUE (author_id, slug)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE article_slug_history;
+ALTER TABLE articles
+    DROP COLUMN slug;
+ALTER TABLE users
+    DROP COLUMN username;
+-- +goose StatementEnd
@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE articles
+    ADD COLUMN version integer not null default 1;
+ALTER TABLE users
+    ADD COLUMN version integer not null default 1;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE articles
+    DROP COLUMN version;
+ALTER TABLE users
+    DROP COLUMN version;
+-- +goose StatementEnd
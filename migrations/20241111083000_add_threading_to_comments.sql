@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE comments
+    ADD COLUMN parent_id uuid references comments(id) on delete cascade,
+    ADD COLUMN edited_at timestamptz,
+    ADD COLUMN deleted_at timestamptz;
+CREATE INDEX comments_article_id_parent_id_idx ON comments (article_id, parent_id);
+CREATE INDEX comments_parent_id_idx ON comments (parent_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS comments_parent_id_idx;
+DROP INDEX IF EXISTS comments_article_id_parent_id_idx;
+ALTER TABLE comments
+    DROP COLUMN deleted_at,
+    DROP COLUMN edited_at,
+    DROP COLUMN parent_id;
+-- +goose StatementEnd
@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE notifications(
+    id uuid primary key default gen_random_uuid(),
+    recipient_id uuid not null references users(id) on delete cascade,
+    actor_id uuid not null references users(id) on delete cascade,
+    type text not null check (type IN ('follow', 'like', 'comment', 'reply')),
+    article_id uuid references articles(id) on delete cascade,
+    comment_id uuid references comments(id) on delete cascade,
+    group_key text not null,
+    read_at timestamptz,
+    created_at timestamptz not null default now()
+);
+CREATE INDEX notifications_recipient_group_idx ON notifications (recipient_id, group_key, read_at);
+CREATE INDEX notifications_recipient_unread_idx ON notifications (recipient_id) WHERE read_at IS NULL;
+-- liking or following again after undoing it should not notify twice
+CREATE UNIQUE INDEX notifications_unique_reaction_idx ON notifications (recipient_id, group_key, actor_id)
+    WHERE type IN ('follow', 'like');
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE notifications;
+-- +goose StatementEnd
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE activation_tokens(
+    id serial primary key,
+    user_id uuid not null references users(id) on delete cascade,
+    token_hash text not null,
+    expiration timestamptz not null,
+    created_at timestamptz not null default now(),
+    CONSTRAINT unique_activation_token_hash UNIQUE (token_hash)
+);
+CREATE INDEX activation_tokens_user_id_idx ON activation_tokens (user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE activation_tokens;
+-- +goose StatementEnd
@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Every refresh token is a row. Rotating a token marks its row rotated and
+-- inserts the next token under the same family_id, so a family is one signed
+-- in device and a rotated token showing up again means it was stolen.
+CREATE TABLE sessions(
+    id uuid primary key default gen_random_uuid(),
+    family_id uuid not null,
+    user_id uuid not null references users(id) on delete cascade,
+    token_hash text not null,
+    user_agent text not null default '',
+    ip_address text not null default '',
+    signed_in_at timestamptz not null default now(),
+    created_at timestamptz not null default now(),
+    expires_at timestamptz not null,
+    rotated_at timestamptz,
+    revoked_at timestamptz,
+    CONSTRAINT unique_session_token_hash UNIQUE (token_hash)
+);
+CREATE INDEX sessions_family_id_idx ON sessions (family_id);
+CREATE INDEX sessions_user_active_idx ON sessions (user_id) WHERE rotated_at IS NULL AND revoked_at IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE sessions;
+-- +goose StatementEnd
@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE users
+    ADD COLUMN role text not null default 'author',
+    ADD CONSTRAINT users_role_check CHECK (role IN ('reader', 'author', 'editor', 'admin'));
+
+CREATE TABLE role_changes(
+    id serial primary key,
+    user_id uuid not null references users(id) on delete cascade,
+    changed_by uuid references users(id) on delete set null,
+    old_role text not null,
+    new_role text not null,
+    reason text not null default '',
+    created_at timestamptz not null default now()
+);
+CREATE INDEX role_changes_user_id_idx ON role_changes (user_id, created_at DESC);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE role_changes;
+ALTER TABLE users DROP COLUMN role;
+-- +goose StatementEnd
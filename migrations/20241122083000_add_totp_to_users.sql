@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE users
+    ADD COLUMN totp_secret text,
+    ADD COLUMN totp_enabled boolean not null default false,
+    ADD COLUMN totp_last_step bigint not null default 0;
+
+CREATE TABLE mfa_recovery_codes(
+    id serial primary key,
+    user_id uuid not null references users(id) on delete cascade,
+    code_hash text not null,
+    used_at timestamptz,
+    created_at timestamptz not null default now(),
+    CONSTRAINT unique_recovery_code_hash UNIQUE (user_id, code_hash)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE mfa_recovery_codes;
+ALTER TABLE users
+    DROP COLUMN totp_secret,
+    DROP COLUMN totp_enabled,
+    DROP COLUMN totp_last_step;
+-- +goose StatementEnd
@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+-- the feed pages through published articles newest first by (published_at, id)
+CREATE INDEX articles_published_feed_idx ON articles (published_at DESC, id DESC) WHERE status = 'published';
+CREATE INDEX articles_author_published_idx ON articles (author_id, published_at DESC) WHERE status = 'published';
+CREATE INDEX followers_follower_idx ON followers (follower_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX followers_follower_idx;
+DROP INDEX articles_author_published_idx;
+DROP INDEX articles_published_feed_idx;
+-- +goose StatementEnd
@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE tag_follows(
+    user_id uuid not null references users(id) on delete cascade,
+    tag_id integer not null references tags(id) on delete cascade,
+    followed_at timestamptz not null default now(),
+    primary key (user_id, tag_id)
+);
+CREATE INDEX tag_follows_tag_idx ON tag_follows (tag_id);
+
+CREATE TABLE category_follows(
+    user_id uuid not null references users(id) on delete cascade,
+    category_id integer not null references categories(id) on delete cascade,
+    followed_at timestamptz not null default now(),
+    primary key (user_id, category_id)
+);
+CREATE INDEX category_follows_category_idx ON category_follows (category_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE category_follows;
+DROP TABLE tag_follows;
+-- +goose StatementEnd
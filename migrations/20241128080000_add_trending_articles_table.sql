@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+-- trending scores use when an article was saved. Saves made before now
+-- are dated to the epoch, as their real time is unknown and stamping them
+-- now() would count every one of them as brand new.
+ALTER TABLE saved_articles ADD COLUMN saved_at timestamptz;
+UPDATE saved_articles SET saved_at = 'epoch';
+ALTER TABLE saved_articles
+    ALTER COLUMN saved_at SET DEFAULT now(),
+    ALTER COLUMN saved_at SET NOT NULL;
+
+-- refreshed wholesale, one period at a time, by the trending worker
+CREATE TABLE trending_articles(
+    period text not null,
+    article_id uuid not null references articles(id) on delete cascade,
+    score double precision not null,
+    likes integer not null default 0,
+    comments integer not null default 0,
+    saves integer not null default 0,
+    views integer not null default 0,
+    computed_at timestamptz not null default now(),
+    primary key (period, article_id)
+);
+CREATE INDEX trending_articles_period_score_idx ON trending_articles (period, score DESC);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE trending_articles;
+ALTER TABLE saved_articles DROP COLUMN saved_at;
+-- +goose StatementEnd
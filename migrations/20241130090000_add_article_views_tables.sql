@@ -0,0 +1,32 @@
+-- +goose Up
+-- +goose StatementBegin
+-- One row per counted view. viewer_key is "user:<id>" for signed in readers
+-- and "anon:<sha256 of ip and user agent>" for everyone else; a viewer is
+-- counted again once their last view is 30 minutes old.
+CREATE TABLE article_views(
+    id bigserial primary key,
+    article_id uuid not null references articles(id) on delete cascade,
+    viewer_key text not null,
+    viewed_at timestamptz not null default now(),
+    read_depth smallint not null default 0 check (read_depth BETWEEN 0 AND 100),
+    time_on_page integer not null default 0,
+    read_at timestamptz
+);
+CREATE INDEX article_views_viewer_idx ON article_views (article_id, viewer_key, viewed_at DESC);
+CREATE INDEX article_views_viewed_at_idx ON article_views (viewed_at);
+
+-- daily counters, bumped once per flushed batch rather than once per view
+CREATE TABLE article_daily_stats(
+    article_id uuid not null references articles(id) on delete cascade,
+    day date not null,
+    views integer not null default 0,
+    reads integer not null default 0,
+    primary key (article_id, day)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE article_daily_stats;
+DROP TABLE article_views;
+-- +goose StatementEnd
@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE email_change_tokens(
+    id serial primary key,
+    user_id uuid not null references users(id) on delete cascade,
+    new_email text not null,
+    token_hash text not null,
+    expiration timestamptz not null,
+    created_at timestamptz not null default now(),
+    CONSTRAINT unique_email_change_token_hash UNIQUE (token_hash)
+);
+CREATE INDEX email_change_tokens_user_id_idx ON email_change_tokens (user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE email_change_tokens;
+-- +goose StatementEnd